@@ -2,9 +2,15 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"os"
+	"slices"
+	"time"
+
+	"github.com/anantashahane/Chirpy/internal/database"
+	"github.com/google/uuid"
 )
 
 func (apiCfg *apiConfig) metricsHandler(responseWriter http.ResponseWriter, req *http.Request) {
@@ -45,3 +51,61 @@ func (apiCfg *apiConfig) resetHandler(responseWriter http.ResponseWriter, req *h
 		}
 	}
 }
+
+func (apiCfg *apiConfig) setRoleHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	type requestBody struct {
+		Role string `json:"role"`
+	}
+
+	uid, err := uuid.Parse(req.PathValue("userID"))
+	if err != nil {
+		responseWriter.WriteHeader(404)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Error parsing user ID."))
+		return
+	}
+
+	decoder := json.NewDecoder(req.Body)
+	decoder.DisallowUnknownFields()
+	requestData := requestBody{}
+	if err := decoder.Decode(&requestData); err != nil {
+		responseWriter.WriteHeader(400)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Json Decode failed."))
+		return
+	}
+	if !slices.Contains([]string{roleUser, roleAdmin}, requestData.Role) {
+		responseWriter.WriteHeader(400)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Unknown role " + requestData.Role))
+		return
+	}
+
+	admin, _ := userFromContext(req.Context())
+	if admin.ID == uid && requestData.Role != roleAdmin {
+		responseWriter.WriteHeader(409)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Admins cannot demote themselves."))
+		return
+	}
+
+	userData, err := apiCfg.db.SetUserRole(context.Background(), database.SetUserRoleParams{
+		Role:      requestData.Role,
+		UpdatedAt: time.Now(),
+		ID:        uid,
+	})
+	if err != nil {
+		responseWriter.WriteHeader(404)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("No such user " + uid.String()))
+		return
+	}
+
+	responseWriter.Header().Set("Content-Type", "application/json")
+	responseWriter.WriteHeader(200)
+	json.NewEncoder(responseWriter).Encode(struct {
+		ID    string `json:"id"`
+		Email string `json:"email"`
+		Role  string `json:"role"`
+	}{ID: userData.ID.String(), Email: userData.Email, Role: userData.Role})
+}
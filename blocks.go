@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/anantashahane/Chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+type relationResponseBody struct {
+	UserID    string `json:"user_id"`
+	CreatedAt string `json:"created_at"`
+}
+
+// targetUserFromPath reads the user named in the path, answering the request
+// itself when it is the caller or no such user exists.
+func (apiCfg *apiConfig) targetUserFromPath(responseWriter http.ResponseWriter, req *http.Request, callerID uuid.UUID) (uuid.UUID, bool) {
+	userID, err := uuid.Parse(req.PathValue("userID"))
+	if err != nil {
+		responseWriter.WriteHeader(404)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Error parsing user id."))
+		return uuid.Nil, false
+	}
+	if userID == callerID {
+		responseWriter.WriteHeader(400)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("You can't do that to yourself."))
+		return uuid.Nil, false
+	}
+
+	user, err := apiCfg.db.GetUserByID(context.Background(), userID)
+	if err != nil || user.DeletionRequestedAt.Valid {
+		responseWriter.WriteHeader(404)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("No such user " + userID.String()))
+		return uuid.Nil, false
+	}
+	return user.ID, true
+}
+
+// blockUserHandler hides the caller and the target from each other. The
+// filtering lives in the chirp queries, so existing bookmarks and profile
+// views stop showing the other side's chirps without being deleted.
+func (apiCfg *apiConfig) blockUserHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	userData, _ := userFromContext(req.Context())
+
+	targetID, ok := apiCfg.targetUserFromPath(responseWriter, req, userData.ID)
+	if !ok {
+		return
+	}
+
+	// Blocking twice keeps the original block.
+	err := apiCfg.db.CreateBlock(context.Background(), database.CreateBlockParams{
+		BlockerID: userData.ID,
+		BlockedID: targetID,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		responseWriter.WriteHeader(500)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Unable to block user."))
+		return
+	}
+	responseWriter.WriteHeader(204)
+}
+
+func (apiCfg *apiConfig) unblockUserHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	userData, _ := userFromContext(req.Context())
+
+	targetID, err := uuid.Parse(req.PathValue("userID"))
+	if err != nil {
+		responseWriter.WriteHeader(404)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Error parsing user id."))
+		return
+	}
+
+	err = apiCfg.db.DeleteBlock(context.Background(), database.DeleteBlockParams{
+		BlockerID: userData.ID,
+		BlockedID: targetID,
+	})
+	if err != nil {
+		responseWriter.WriteHeader(500)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Unable to unblock user."))
+		return
+	}
+	responseWriter.WriteHeader(204)
+}
+
+func (apiCfg *apiConfig) listBlocksHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	userData, _ := userFromContext(req.Context())
+
+	blocks, err := apiCfg.db.ListBlocks(context.Background(), userData.ID)
+	if err != nil {
+		responseWriter.WriteHeader(500)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Internal Server failed to access database."))
+		return
+	}
+
+	responseBody := []relationResponseBody{}
+	for _, block := range blocks {
+		responseBody = append(responseBody, relationResponseBody{
+			UserID:    block.BlockedID.String(),
+			CreatedAt: block.CreatedAt.String(),
+		})
+	}
+	responseWriter.Header().Set("Content-Type", "application/json")
+	responseWriter.WriteHeader(200)
+	json.NewEncoder(responseWriter).Encode(responseBody)
+}
+
+// muteUserHandler hides the target's chirps from the caller's timeline. The
+// target isn't told and can still see the caller.
+func (apiCfg *apiConfig) muteUserHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	userData, _ := userFromContext(req.Context())
+
+	targetID, ok := apiCfg.targetUserFromPath(responseWriter, req, userData.ID)
+	if !ok {
+		return
+	}
+
+	err := apiCfg.db.CreateMute(context.Background(), database.CreateMuteParams{
+		MuterID:   userData.ID,
+		MutedID:   targetID,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		responseWriter.WriteHeader(500)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Unable to mute user."))
+		return
+	}
+	responseWriter.WriteHeader(204)
+}
+
+func (apiCfg *apiConfig) unmuteUserHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	userData, _ := userFromContext(req.Context())
+
+	targetID, err := uuid.Parse(req.PathValue("userID"))
+	if err != nil {
+		responseWriter.WriteHeader(404)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Error parsing user id."))
+		return
+	}
+
+	err = apiCfg.db.DeleteMute(context.Background(), database.DeleteMuteParams{
+		MuterID: userData.ID,
+		MutedID: targetID,
+	})
+	if err != nil {
+		responseWriter.WriteHeader(500)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Unable to unmute user."))
+		return
+	}
+	responseWriter.WriteHeader(204)
+}
+
+func (apiCfg *apiConfig) listMutesHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	userData, _ := userFromContext(req.Context())
+
+	mutes, err := apiCfg.db.ListMutes(context.Background(), userData.ID)
+	if err != nil {
+		responseWriter.WriteHeader(500)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Internal Server failed to access database."))
+		return
+	}
+
+	responseBody := []relationResponseBody{}
+	for _, mute := range mutes {
+		responseBody = append(responseBody, relationResponseBody{
+			UserID:    mute.MutedID.String(),
+			CreatedAt: mute.CreatedAt.String(),
+		})
+	}
+	responseWriter.Header().Set("Content-Type", "application/json")
+	responseWriter.WriteHeader(200)
+	json.NewEncoder(responseWriter).Encode(responseBody)
+}
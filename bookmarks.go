@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/anantashahane/Chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+type bookmarkResponseBody struct {
+	chirpResponseBody
+	BookmarkedAt string `json:"bookmarked_at"`
+}
+
+func (apiCfg *apiConfig) bookmarkChirpHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	userData, _ := userFromContext(req.Context())
+
+	chirpID, err := uuid.Parse(req.PathValue("chirpID"))
+	if err != nil {
+		responseWriter.WriteHeader(404)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Error parsing chirp id."))
+		return
+	}
+
+	chirp, err := apiCfg.db.GetChirpByID(context.Background(), database.GetChirpByIDParams{
+		ID:       chirpID,
+		ViewerID: userData.ID,
+	})
+	if err != nil || !chirp.Published {
+		responseWriter.WriteHeader(404)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Chirp with ID " + chirpID.String() + " not found"))
+		return
+	}
+
+	// Bookmarking twice keeps the original bookmark.
+	err = apiCfg.db.CreateBookmark(context.Background(), database.CreateBookmarkParams{
+		UserID:    userData.ID,
+		ChirpID:   chirp.ID,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		responseWriter.WriteHeader(500)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Unable to save bookmark."))
+		return
+	}
+	responseWriter.WriteHeader(204)
+}
+
+func (apiCfg *apiConfig) removeBookmarkHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	userData, _ := userFromContext(req.Context())
+
+	chirpID, err := uuid.Parse(req.PathValue("chirpID"))
+	if err != nil {
+		responseWriter.WriteHeader(404)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Error parsing chirp id."))
+		return
+	}
+
+	err = apiCfg.db.DeleteBookmark(context.Background(), database.DeleteBookmarkParams{
+		UserID:  userData.ID,
+		ChirpID: chirpID,
+	})
+	if err != nil {
+		responseWriter.WriteHeader(500)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Unable to remove bookmark."))
+		return
+	}
+	responseWriter.WriteHeader(204)
+}
+
+// listBookmarksHandler pages through the caller's bookmarks, newest first.
+// Bookmarks of chirps that were deleted, or whose author left, are skipped.
+func (apiCfg *apiConfig) listBookmarksHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	type responseBody struct {
+		Bookmarks  []bookmarkResponseBody `json:"bookmarks"`
+		NextCursor string                 `json:"next_cursor,omitempty"`
+	}
+
+	userData, _ := userFromContext(req.Context())
+
+	cursor, err := parsePageCursor(req.URL.Query().Get("cursor"))
+	if err != nil {
+		responseWriter.WriteHeader(400)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte(err.Error()))
+		return
+	}
+	limit := pageLimit(req)
+	includeSensitive, status, err := apiCfg.includeSensitive(req, userData.ID)
+	if err != nil {
+		responseWriter.WriteHeader(status)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte(err.Error()))
+		return
+	}
+
+	bookmarks, err := apiCfg.db.GetBookmarks(context.Background(), database.GetBookmarksParams{
+		UserID:           userData.ID,
+		Before:           cursor.Time,
+		BeforeID:         cursor.ID,
+		IncludeSensitive: includeSensitive,
+		MaxResults:       limit,
+	})
+	if err != nil {
+		responseWriter.WriteHeader(500)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Internal Server failed to access database."))
+		return
+	}
+
+	responseData := responseBody{Bookmarks: []bookmarkResponseBody{}}
+	for _, bookmark := range bookmarks {
+		responseData.Bookmarks = append(responseData.Bookmarks, bookmarkResponseBody{
+			chirpResponseBody: chirpResponse(bookmark.Chirp),
+			BookmarkedAt:      bookmark.BookmarkedAt.String(),
+		})
+	}
+	if len(bookmarks) == int(limit) {
+		last := bookmarks[len(bookmarks)-1]
+		responseData.NextCursor = pageCursor{Time: last.BookmarkedAt, ID: last.Chirp.ID}.String()
+	}
+
+	responseWriter.Header().Set("Content-Type", "application/json")
+	responseWriter.WriteHeader(200)
+	json.NewEncoder(responseWriter).Encode(responseData)
+}
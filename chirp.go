@@ -2,36 +2,246 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"net/http"
 	"sort"
 	"strings"
 	"time"
 
-	"github.com/anantashahane/Chirpy/internal/auth"
 	"github.com/anantashahane/Chirpy/internal/database"
+	"github.com/anantashahane/Chirpy/internal/entitlements"
+	"github.com/anantashahane/Chirpy/internal/jobs"
 	"github.com/google/uuid"
 )
 
 type chirpResponseBody struct {
-	ID        string `json:"id"`
-	CreatedAt string `json:"created_at"`
-	UpdatedAt string `json:"updated_at"`
-	Body      string `json:"body"`
-	UserID    string `json:"user_id"`
+	ID             string `json:"id"`
+	CreatedAt      string `json:"created_at"`
+	UpdatedAt      string `json:"updated_at"`
+	Body           string `json:"body"`
+	UserID         string `json:"user_id"`
+	PublishAt      string `json:"publish_at,omitempty"`
+	DeletedAt      string `json:"deleted_at,omitempty"`
+	IsPinned       bool   `json:"is_pinned"`
+	Visibility     string `json:"visibility"`
+	ContentWarning string `json:"content_warning,omitempty"`
+	Sensitive      bool   `json:"sensitive"`
 }
 
-func validateChirp(body string) bool {
-	if len(body) > 140 {
+// maxContentWarningLength caps the text clients show in place of a
+// collapsed chirp.
+const maxContentWarningLength = 200
+
+// Visibilities stored in chirps.visibility. Unlisted chirps can be opened by
+// ID but stay out of feeds. Followers-only chirps are only served to the
+// author's followers, and as nobody can follow anyone yet, to the author.
+const (
+	visibilityPublic    = "public"
+	visibilityFollowers = "followers"
+	visibilityUnlisted  = "unlisted"
+)
+
+func chirpResponse(chirp database.Chirp) chirpResponseBody {
+	responseData := chirpResponseBody{
+		ID:             chirp.ID.String(),
+		CreatedAt:      chirp.CreatedAt.String(),
+		UpdatedAt:      chirp.UpdatedAt.String(),
+		Body:           chirp.Body,
+		UserID:         chirp.UserID.String(),
+		IsPinned:       chirp.PinnedPosition.Valid,
+		Visibility:     chirp.Visibility,
+		ContentWarning: chirp.ContentWarning,
+		Sensitive:      chirp.Sensitive,
+	}
+	if !chirp.Published {
+		responseData.PublishAt = chirp.PublishAt.Time.Format(time.RFC3339)
+	}
+	if chirp.DeletedAt.Valid {
+		responseData.DeletedAt = chirp.DeletedAt.Time.String()
+	}
+	return responseData
+}
+
+// includeSensitive reports whether feeds should carry chirps that have a
+// content warning or are marked sensitive. ?sensitive=include or
+// ?sensitive=omit decides, otherwise signed in viewers get their profile
+// preference and everyone else gets them. Viewers always see their own. On
+// failure it returns the status to answer with.
+func (apiCfg *apiConfig) includeSensitive(req *http.Request, viewerID uuid.UUID) (bool, int, error) {
+	switch req.URL.Query().Get("sensitive") {
+	case "include":
+		return true, 0, nil
+	case "omit":
+		return false, 0, nil
+	case "":
+	default:
+		return false, 400, errors.New("Sensitive must be include or omit.")
+	}
+	if viewerID == uuid.Nil {
+		return true, 0, nil
+	}
+	viewer, err := apiCfg.db.GetUserByID(context.Background(), viewerID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return true, 0, nil
+	}
+	if err != nil {
+		return false, 500, errors.New("Internal Server failed to access database.")
+	}
+	return !viewer.HideSensitive, 0, nil
+}
+
+func validateChirp(body string, maxLength int) bool {
+	if len(body) > maxLength {
 		return false
 	}
 	return true
 }
 
+// chirpLengthAllowed checks body against the plan, writing 402 when only
+// Chirpy Red allows the length and 406 when no plan does.
+func chirpLengthAllowed(responseWriter http.ResponseWriter, body string, plan entitlements.Plan) bool {
+	if validateChirp(body, plan.MaxChirpLength) {
+		return true
+	}
+	if validateChirp(body, entitlements.ForUser(true).MaxChirpLength) {
+		entitlementErrorWriter(responseWriter, plan.Require(entitlements.LongChirps))
+		return false
+	}
+	responseWriter.WriteHeader(406)
+	responseWriter.Header().Set("Content Type", "plain/text")
+	responseWriter.Write([]byte("Chirp too long."))
+	return false
+}
+
+// newChirp is what an author asks to post.
+type newChirp struct {
+	Body      string
+	PublishAt *time.Time
+	// Visibility is one of the visibility constants, public when empty.
+	Visibility string
+	// ContentWarning and Sensitive let clients collapse the body.
+	ContentWarning string
+	Sensitive      bool
+}
+
+// notFoundError is returned by a createChirp callback when what it works on
+// has gone, so the request is answered 404 with message instead of 422.
+type notFoundError struct {
+	message string
+}
+
+func (e notFoundError) Error() string { return e.message }
+
+// createChirp is the one path chirps are created through. It applies the
+// plan's scheduling, length and rate limits, answering the request itself
+// when one fails, then saves the chirp and its side effects in a single
+// transaction along with whatever also writes. It reports whether the chirp
+// was created.
+func (apiCfg *apiConfig) createChirp(responseWriter http.ResponseWriter, userID uuid.UUID, plan entitlements.Plan, chirp newChirp, also func(q *database.Queries) error) (chirpResponseBody, bool) {
+	// A publish_at in the past or present just publishes now.
+	scheduled := chirp.PublishAt != nil && chirp.PublishAt.After(time.Now())
+	if scheduled {
+		if err := plan.Require(entitlements.ScheduledChirps); err != nil {
+			entitlementErrorWriter(responseWriter, err)
+			return chirpResponseBody{}, false
+		}
+	}
+
+	if chirp.Visibility == "" {
+		chirp.Visibility = visibilityPublic
+	}
+	if chirp.Visibility != visibilityPublic && chirp.Visibility != visibilityFollowers && chirp.Visibility != visibilityUnlisted {
+		responseWriter.WriteHeader(400)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Visibility must be public, followers or unlisted."))
+		return chirpResponseBody{}, false
+	}
+	if !validateChirp(chirp.ContentWarning, maxContentWarningLength) {
+		responseWriter.WriteHeader(400)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Content warnings can have at most 200 characters."))
+		return chirpResponseBody{}, false
+	}
+
+	if !chirpLengthAllowed(responseWriter, chirp.Body, plan) {
+		return chirpResponseBody{}, false
+	}
+	if !apiCfg.allowChirpWrite(responseWriter, plan, userID) {
+		return chirpResponseBody{}, false
+	}
+
+	chirpParams := database.CreateChirpsParams{
+		ID:             uuid.New(),
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+		Body:           chirp.Body,
+		UserID:         userID,
+		Published:      true,
+		Visibility:     chirp.Visibility,
+		ContentWarning: chirp.ContentWarning,
+		Sensitive:      chirp.Sensitive,
+	}
+	if scheduled {
+		// Scheduled chirps take their place in the timeline at publish time.
+		// The columns hold local time without a zone, so a publish_at sent
+		// with another offset is converted first rather than stored as is.
+		publishAt := chirp.PublishAt.In(time.Local)
+		chirpParams.CreatedAt = publishAt
+		chirpParams.Published = false
+		chirpParams.PublishAt = sql.NullTime{Time: publishAt, Valid: true}
+	}
+
+	var responseData chirpResponseBody
+	err := apiCfg.inTx(context.Background(), func(q *database.Queries) error {
+		savedData, err := q.CreateChirps(context.Background(), chirpParams)
+		if err != nil {
+			return err
+		}
+		responseData = chirpResponse(savedData)
+		if scheduled {
+			_, err = jobs.Enqueue(context.Background(), q, jobPublishChirp, publishChirpJob{ChirpID: savedData.ID}, jobs.Options{
+				RunAt: savedData.PublishAt.Time,
+			})
+		} else {
+			err = emitEvent(context.Background(), q, "chirp.created", savedData, responseData)
+			if err == nil {
+				err = publishStreamEvent(context.Background(), q, "chirp.created", savedData, responseData)
+			}
+			if err == nil {
+				err = notifyMentions(context.Background(), q, savedData)
+			}
+		}
+		if err == nil && also != nil {
+			err = also(q)
+		}
+		return err
+	})
+	notFound := notFoundError{}
+	if errors.As(err, &notFound) {
+		responseWriter.WriteHeader(404)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte(notFound.message))
+		return chirpResponseBody{}, false
+	}
+	if err != nil {
+		responseWriter.WriteHeader(422)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Save failed, check if attached user ID exists."))
+		return chirpResponseBody{}, false
+	}
+
+	return responseData, true
+}
+
 func (apiCfg *apiConfig) createChirpHandler(responseWriter http.ResponseWriter, req *http.Request) {
 	type requestBody struct {
-		Body string `json:"body"`
+		Body           string     `json:"body"`
+		PublishAt      *time.Time `json:"publish_at"`
+		Visibility     string     `json:"visibility"`
+		ContentWarning string     `json:"content_warning"`
+		Sensitive      bool       `json:"sensitive"`
 	}
 
 	encoder := json.NewEncoder(responseWriter)
@@ -47,49 +257,24 @@ func (apiCfg *apiConfig) createChirpHandler(responseWriter http.ResponseWriter,
 		return
 	}
 
-	if !validateChirp(requestData.Body) {
-		responseWriter.WriteHeader(406)
-		responseWriter.Header().Set("Content Type", "plain/text")
-		responseWriter.Write([]byte("Chirp too long."))
-		return
-	}
-
-	tokenString, err := auth.GetBearerToken(req.Header)
-	if err != nil {
-		responseWriter.WriteHeader(406)
-		responseWriter.Header().Set("Content Type", "plain/text")
-		responseWriter.Write([]byte(fmt.Sprintf("Error reading authorisation from header. %s", err.Error())))
-		return
-	}
-
-	uid, err := auth.ValidateJWT(tokenString, apiCfg.secret)
-	if err != nil {
-		responseWriter.WriteHeader(401)
-		responseWriter.Header().Set("Content Type", "plain/text")
-		responseWriter.Write(fmt.Appendf([]byte{}, "Error parsing user from JWT token. %s", err.Error()))
-		return
-	}
-
-	savedData, err := apiCfg.db.CreateChirps(context.Background(), database.CreateChirpsParams{
-		ID:        uuid.New(),
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-		Body:      requestData.Body,
-		UserID:    uid,
-	})
+	userData, _ := userFromContext(req.Context())
+	plan, err := apiCfg.planFor(context.Background(), userData.ID)
 	if err != nil {
-		responseWriter.WriteHeader(422)
+		responseWriter.WriteHeader(500)
 		responseWriter.Header().Set("Content-Type", "plain/text")
-		responseWriter.Write([]byte("Save failed, check if attached user ID exists."))
+		responseWriter.Write([]byte("Internal Server failed to access database."))
 		return
 	}
 
-	responseData := chirpResponseBody{
-		ID:        savedData.ID.String(),
-		CreatedAt: savedData.CreatedAt.String(),
-		UpdatedAt: savedData.UpdatedAt.String(),
-		Body:      savedData.Body,
-		UserID:    savedData.UserID.String(),
+	responseData, ok := apiCfg.createChirp(responseWriter, userData.ID, plan, newChirp{
+		Body:           requestData.Body,
+		PublishAt:      requestData.PublishAt,
+		Visibility:     requestData.Visibility,
+		ContentWarning: requestData.ContentWarning,
+		Sensitive:      requestData.Sensitive,
+	}, nil)
+	if !ok {
+		return
 	}
 
 	responseWriter.WriteHeader(201)
@@ -109,7 +294,21 @@ func (apiCfg *apiConfig) getAllChirpsHandler(responseWriter http.ResponseWriter,
 
 	responseBody := []chirpResponseBody{}
 
-	chirps, err := apiCfg.db.GetChirps(context.Background())
+	viewerID := apiCfg.viewerFromRequest(req)
+	includeSensitive, status, err := apiCfg.includeSensitive(req, viewerID)
+	if err != nil {
+		responseWriter.WriteHeader(status)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte(err.Error()))
+		return
+	}
+
+	// Signed in viewers don't see chirps from users they blocked, were blocked
+	// by, or muted. Only public chirps are listed, apart from the viewer's own.
+	chirps, err := apiCfg.db.GetChirps(context.Background(), database.GetChirpsParams{
+		ViewerID:         viewerID,
+		IncludeSensitive: includeSensitive,
+	})
 	if err != nil {
 		responseWriter.WriteHeader(500)
 		responseWriter.Header().Set("Content-Type", "plain/text")
@@ -126,13 +325,7 @@ func (apiCfg *apiConfig) getAllChirpsHandler(responseWriter http.ResponseWriter,
 
 	for _, chirp := range chirps {
 		if filterAuthor == "" || filterAuthor == chirp.UserID.String() {
-			responseBody = append(responseBody, chirpResponseBody{
-				ID:        chirp.ID.String(),
-				UpdatedAt: chirp.UpdatedAt.String(),
-				CreatedAt: chirp.CreatedAt.String(),
-				Body:      chirp.Body,
-				UserID:    chirp.UserID.String(),
-			})
+			responseBody = append(responseBody, chirpResponse(chirp))
 		}
 	}
 
@@ -156,20 +349,25 @@ func (apiCfg *apiConfig) handleGetChirpByID(responseWriter http.ResponseWriter,
 		responseWriter.Write([]byte("Error parsing ID " + path))
 		return
 	}
-	responseBody := chirpResponseBody{}
-
-	dbChirp, err := apiCfg.db.GetChirpByID(context.Background(), id)
+	viewerID := apiCfg.viewerFromRequest(req)
+	dbChirp, err := apiCfg.db.GetChirpByID(context.Background(), database.GetChirpByIDParams{
+		ID:       id,
+		ViewerID: viewerID,
+	})
 	if err != nil {
+		if tombstone, err := apiCfg.db.GetChirpTombstone(context.Background(), database.GetChirpTombstoneParams{
+			ID:       id,
+			ViewerID: viewerID,
+		}); err == nil {
+			chirpGoneWriter(responseWriter, tombstone)
+			return
+		}
 		responseWriter.WriteHeader(404)
 		responseWriter.Header().Set("Content-Type", "plain/text")
 		responseWriter.Write([]byte("No such chirp with ID " + path))
 		return
 	}
-	responseBody.ID = dbChirp.ID.String()
-	responseBody.CreatedAt = dbChirp.CreatedAt.String()
-	responseBody.UpdatedAt = dbChirp.UpdatedAt.String()
-	responseBody.Body = dbChirp.Body
-	responseBody.UserID = dbChirp.UserID.String()
+	responseBody := chirpResponse(dbChirp)
 
 	encoder := json.NewEncoder(responseWriter)
 	responseWriter.WriteHeader(200)
@@ -194,45 +392,204 @@ func (apiCfg *apiConfig) deleteChirpHandler(responseWriter http.ResponseWriter,
 		responseWriter.Write([]byte("Error parsing chirp id."))
 		return
 	}
-	// Authenticate sender.
-	authString, err := auth.GetBearerToken(req.Header)
+	userData, _ := userFromContext(req.Context())
+	uid := userData.ID
 
+	chirpToDelete, err := apiCfg.db.GetChirpByID(context.Background(), database.GetChirpByIDParams{
+		ID:       chirpID,
+		ViewerID: uid,
+	})
 	if err != nil {
-		responseWriter.WriteHeader(401)
+		responseWriter.WriteHeader(404)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Chirp with ID " + chirpID.String() + " not found"))
+		return
+	}
+	if uid != chirpToDelete.UserID {
+		responseWriter.WriteHeader(403)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Unauthorised access to delete this tweet."))
+		return
+	}
+	err = apiCfg.inTx(context.Background(), func(q *database.Queries) error {
+		_, err := q.SoftDeleteChirp(context.Background(), database.SoftDeleteChirpParams{
+			ID:        chirpToDelete.ID,
+			DeletedAt: sql.NullTime{Time: time.Now(), Valid: true},
+		})
+		if err != nil || !chirpToDelete.Published {
+			return err
+		}
+		deleted := struct {
+			ID     string `json:"id"`
+			UserID string `json:"user_id"`
+		}{ID: chirpToDelete.ID.String(), UserID: uid.String()}
+		if err := emitEvent(context.Background(), q, "chirp.deleted", chirpToDelete, deleted); err != nil {
+			return err
+		}
+		return publishStreamEvent(context.Background(), q, "chirp.deleted", chirpToDelete, deleted)
+	})
+	if err != nil {
+		responseWriter.WriteHeader(403)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Unable to delete."))
+		return
+	}
+	responseWriter.WriteHeader(204)
+}
+
+// updateChirpHandler edits a chirp's body, content warning or sensitive flag,
+// leaving out whatever the request leaves out. Editing the body needs
+// chirp_editing; authors on any plan can add or change the other two.
+func (apiCfg *apiConfig) updateChirpHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	type requestBody struct {
+		Body           *string `json:"body"`
+		ContentWarning *string `json:"content_warning"`
+		Sensitive      *bool   `json:"sensitive"`
+	}
+
+	userData, _ := userFromContext(req.Context())
+	plan, err := apiCfg.planFor(context.Background(), userData.ID)
+	if err != nil {
+		responseWriter.WriteHeader(500)
 		responseWriter.Header().Set("Content-Type", "plain/text")
-		responseWriter.Write([]byte("Unable to get authorisation Token."))
-		fmt.Println(req.Header)
+		responseWriter.Write([]byte("Internal Server failed to access database."))
 		return
 	}
 
-	//Delete and handle resonse.
-	uid, err := auth.ValidateJWT(authString, apiCfg.secret)
+	chirpID, err := uuid.Parse(req.PathValue("chirpID"))
 	if err != nil {
-		responseWriter.WriteHeader(401)
+		responseWriter.WriteHeader(404)
 		responseWriter.Header().Set("Content-Type", "plain/text")
-		responseWriter.Write([]byte("Unable to validate authorisation Token."))
+		responseWriter.Write([]byte("Error parsing chirp id."))
 		return
 	}
 
-	chirpToDelete, err := apiCfg.db.GetChirpByID(context.Background(), chirpID)
+	decoder := json.NewDecoder(req.Body)
+	decoder.DisallowUnknownFields()
+	requestData := requestBody{}
+	if err := decoder.Decode(&requestData); err != nil {
+		responseWriter.WriteHeader(406)
+		responseWriter.Header().Set("Content Type", "plain/text")
+		responseWriter.Write([]byte("Json Decode failed."))
+		return
+	}
+
+	chirpToEdit, err := apiCfg.db.GetChirpByID(context.Background(), database.GetChirpByIDParams{
+		ID:       chirpID,
+		ViewerID: userData.ID,
+	})
 	if err != nil {
 		responseWriter.WriteHeader(404)
 		responseWriter.Header().Set("Content-Type", "plain/text")
 		responseWriter.Write([]byte("Chirp with ID " + chirpID.String() + " not found"))
 		return
 	}
-	if uid != chirpToDelete.UserID {
+	if userData.ID != chirpToEdit.UserID {
 		responseWriter.WriteHeader(403)
 		responseWriter.Header().Set("Content-Type", "plain/text")
-		responseWriter.Write([]byte("Unauthorised access to delete this tweet."))
+		responseWriter.Write([]byte("Unauthorised access to edit this chirp."))
 		return
 	}
-	_, err = apiCfg.db.DeleteChirpByID(context.Background(), chirpToDelete.ID)
-	if uid != chirpToDelete.UserID {
-		responseWriter.WriteHeader(403)
+
+	update := database.UpdateChirpContentParams{
+		Body:           chirpToEdit.Body,
+		ContentWarning: chirpToEdit.ContentWarning,
+		Sensitive:      chirpToEdit.Sensitive,
+		UpdatedAt:      time.Now(),
+		ID:             chirpToEdit.ID,
+	}
+	if requestData.Body != nil {
+		if err := plan.Require(entitlements.ChirpEditing); err != nil {
+			entitlementErrorWriter(responseWriter, err)
+			return
+		}
+		if !chirpLengthAllowed(responseWriter, *requestData.Body, plan) {
+			return
+		}
+		update.Body = *requestData.Body
+	}
+	if requestData.ContentWarning != nil {
+		if !validateChirp(*requestData.ContentWarning, maxContentWarningLength) {
+			responseWriter.WriteHeader(400)
+			responseWriter.Header().Set("Content-Type", "plain/text")
+			responseWriter.Write([]byte("Content warnings can have at most 200 characters."))
+			return
+		}
+		update.ContentWarning = *requestData.ContentWarning
+	}
+	if requestData.Sensitive != nil {
+		update.Sensitive = *requestData.Sensitive
+	}
+	if !apiCfg.allowChirpWrite(responseWriter, plan, userData.ID) {
+		return
+	}
+
+	var responseData chirpResponseBody
+	err = apiCfg.inTx(context.Background(), func(q *database.Queries) error {
+		savedData, err := q.UpdateChirpContent(context.Background(), update)
+		if err != nil {
+			return err
+		}
+		responseData = chirpResponse(savedData)
+		if !savedData.Published {
+			return nil
+		}
+		return emitEvent(context.Background(), q, "chirp.updated", savedData, responseData)
+	})
+	if err != nil {
+		responseWriter.WriteHeader(500)
 		responseWriter.Header().Set("Content-Type", "plain/text")
-		responseWriter.Write([]byte("Unable to delete."))
+		responseWriter.Write([]byte("Unable to save chirp."))
 		return
 	}
-	responseWriter.WriteHeader(204)
+
+	responseWriter.Header().Set("Content-Type", "application/json")
+	responseWriter.WriteHeader(200)
+	json.NewEncoder(responseWriter).Encode(responseData)
+}
+
+func (apiCfg *apiConfig) chirpAnalyticsHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	type responseBody struct {
+		TotalChirps      int64   `json:"total_chirps"`
+		ChirpsLast7Days  int64   `json:"chirps_last_7_days"`
+		ChirpsLast30Days int64   `json:"chirps_last_30_days"`
+		AverageLength    float64 `json:"average_length"`
+		LongestChirp     int32   `json:"longest_chirp"`
+	}
+
+	userData, _ := userFromContext(req.Context())
+	plan, err := apiCfg.planFor(context.Background(), userData.ID)
+	if err != nil {
+		responseWriter.WriteHeader(500)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Internal Server failed to access database."))
+		return
+	}
+	if err := plan.Require(entitlements.ChirpAnalytics); err != nil {
+		entitlementErrorWriter(responseWriter, err)
+		return
+	}
+
+	now := time.Now()
+	stats, err := apiCfg.db.GetChirpStats(context.Background(), database.GetChirpStatsParams{
+		WeekAgo:  now.AddDate(0, 0, -7),
+		MonthAgo: now.AddDate(0, 0, -30),
+		UserID:   userData.ID,
+	})
+	if err != nil {
+		responseWriter.WriteHeader(500)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Internal Server failed to access database."))
+		return
+	}
+
+	responseWriter.Header().Set("Content-Type", "application/json")
+	responseWriter.WriteHeader(200)
+	json.NewEncoder(responseWriter).Encode(responseBody{
+		TotalChirps:      stats.TotalChirps,
+		ChirpsLast7Days:  stats.ChirpsLast7Days,
+		ChirpsLast30Days: stats.ChirpsLast30Days,
+		AverageLength:    stats.AverageLength,
+		LongestChirp:     stats.LongestChirp,
+	})
 }
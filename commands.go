@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/anantashahane/Chirpy/internal/database"
+)
+
+// runCommand handles the command line tools, e.g.
+//
+//	chirpy bootstrap-admin user@example.com
+//
+// and returns the process exit code.
+func runCommand(cfg *apiConfig, args []string) int {
+	switch args[0] {
+	case "bootstrap-admin":
+		if len(args) != 2 {
+			fmt.Println("Usage: chirpy bootstrap-admin <email>")
+			return 2
+		}
+		return cfg.bootstrapAdmin(args[1])
+	default:
+		fmt.Println("Unknown command " + args[0])
+		return 2
+	}
+}
+
+// bootstrapAdmin promotes an existing account to admin. It only works while
+// there is no admin yet, further admins are appointed through
+// PUT /admin/users/{userID}/role.
+func (cfg *apiConfig) bootstrapAdmin(email string) int {
+	ctx := context.Background()
+	admins, err := cfg.db.CountUsersWithRole(ctx, roleAdmin)
+	if err != nil {
+		fmt.Println("Unable to read users: " + err.Error())
+		return 1
+	}
+	if admins > 0 {
+		fmt.Printf("%d admin(s) already exist, use PUT /admin/users/{userID}/role instead.\n", admins)
+		return 1
+	}
+
+	userData, err := cfg.db.GetUser(ctx, email)
+	if err != nil {
+		fmt.Println("No such user, " + email)
+		return 1
+	}
+	_, err = cfg.db.SetUserRole(ctx, database.SetUserRoleParams{
+		Role:      roleAdmin,
+		UpdatedAt: time.Now(),
+		ID:        userData.ID,
+	})
+	if err != nil {
+		fmt.Println("Unable to promote user: " + err.Error())
+		return 1
+	}
+	fmt.Println(email + " is now an admin.")
+	return 0
+}
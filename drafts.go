@@ -0,0 +1,304 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/anantashahane/Chirpy/internal/database"
+	"github.com/anantashahane/Chirpy/internal/entitlements"
+	"github.com/google/uuid"
+)
+
+// Drafts may run over the plan's chirp length while being edited, the limit
+// only applies on publish. This caps how much text a draft can hold at all.
+const maxDraftLength = 10000
+
+type draftResponseBody struct {
+	ID             string `json:"id"`
+	CreatedAt      string `json:"created_at"`
+	UpdatedAt      string `json:"updated_at"`
+	Body           string `json:"body"`
+	ContentWarning string `json:"content_warning"`
+	Sensitive      bool   `json:"sensitive"`
+	Length         int    `json:"length"`
+	MaxLength      int    `json:"max_length"`
+	TooLong        bool   `json:"too_long"`
+}
+
+func draftResponse(draft database.Draft, plan entitlements.Plan) draftResponseBody {
+	return draftResponseBody{
+		ID:             draft.ID.String(),
+		CreatedAt:      draft.CreatedAt.String(),
+		UpdatedAt:      draft.UpdatedAt.String(),
+		Body:           draft.Body,
+		ContentWarning: draft.ContentWarning,
+		Sensitive:      draft.Sensitive,
+		Length:         len(draft.Body),
+		MaxLength:      plan.MaxChirpLength,
+		TooLong:        !validateChirp(draft.Body, plan.MaxChirpLength),
+	}
+}
+
+// draftContent is what an author writes into a draft.
+type draftContent struct {
+	Body           string `json:"body"`
+	ContentWarning string `json:"content_warning"`
+	Sensitive      bool   `json:"sensitive"`
+}
+
+// decodeDraftBody reads the draft's content and checks the body against
+// maxDraftLength and the content warning against its limit, answering the
+// request itself when any of that fails.
+func decodeDraftBody(responseWriter http.ResponseWriter, req *http.Request) (draftContent, bool) {
+
+	decoder := json.NewDecoder(req.Body)
+	decoder.DisallowUnknownFields()
+	requestData := draftContent{}
+	if err := decoder.Decode(&requestData); err != nil {
+		responseWriter.WriteHeader(406)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Json Decode failed."))
+		return draftContent{}, false
+	}
+	if !validateChirp(requestData.Body, maxDraftLength) {
+		responseWriter.WriteHeader(406)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Draft too long."))
+		return draftContent{}, false
+	}
+	if !validateChirp(requestData.ContentWarning, maxContentWarningLength) {
+		responseWriter.WriteHeader(400)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Content warnings can have at most 200 characters."))
+		return draftContent{}, false
+	}
+	return requestData, true
+}
+
+func (apiCfg *apiConfig) createDraftHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	userData, _ := userFromContext(req.Context())
+	plan, ok := apiCfg.requestPlan(responseWriter, userData.ID)
+	if !ok {
+		return
+	}
+
+	content, ok := decodeDraftBody(responseWriter, req)
+	if !ok {
+		return
+	}
+
+	draft, err := apiCfg.db.CreateDraft(context.Background(), database.CreateDraftParams{
+		ID:             uuid.New(),
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+		UserID:         userData.ID,
+		Body:           content.Body,
+		ContentWarning: content.ContentWarning,
+		Sensitive:      content.Sensitive,
+	})
+	if err != nil {
+		responseWriter.WriteHeader(500)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Unable to save draft."))
+		return
+	}
+
+	responseWriter.Header().Set("Content-Type", "application/json")
+	responseWriter.WriteHeader(201)
+	json.NewEncoder(responseWriter).Encode(draftResponse(draft, plan))
+}
+
+func (apiCfg *apiConfig) listDraftsHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	userData, _ := userFromContext(req.Context())
+	plan, ok := apiCfg.requestPlan(responseWriter, userData.ID)
+	if !ok {
+		return
+	}
+
+	drafts, err := apiCfg.db.ListDrafts(context.Background(), userData.ID)
+	if err != nil {
+		responseWriter.WriteHeader(500)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Internal Server failed to access database."))
+		return
+	}
+
+	responseBody := []draftResponseBody{}
+	for _, draft := range drafts {
+		responseBody = append(responseBody, draftResponse(draft, plan))
+	}
+	responseWriter.Header().Set("Content-Type", "application/json")
+	responseWriter.WriteHeader(200)
+	json.NewEncoder(responseWriter).Encode(responseBody)
+}
+
+// draftFromPath loads the draft named in the path if it belongs to the
+// caller, answering 404 otherwise.
+func (apiCfg *apiConfig) draftFromPath(responseWriter http.ResponseWriter, req *http.Request, userID uuid.UUID) (database.Draft, bool) {
+	draftID, err := uuid.Parse(req.PathValue("draftID"))
+	if err != nil {
+		responseWriter.WriteHeader(404)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Error parsing draft id."))
+		return database.Draft{}, false
+	}
+
+	draft, err := apiCfg.db.GetDraft(context.Background(), database.GetDraftParams{
+		ID:     draftID,
+		UserID: userID,
+	})
+	if err != nil {
+		responseWriter.WriteHeader(404)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("No such draft " + draftID.String()))
+		return database.Draft{}, false
+	}
+	return draft, true
+}
+
+func (apiCfg *apiConfig) getDraftHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	userData, _ := userFromContext(req.Context())
+
+	draft, ok := apiCfg.draftFromPath(responseWriter, req, userData.ID)
+	if !ok {
+		return
+	}
+	plan, ok := apiCfg.requestPlan(responseWriter, userData.ID)
+	if !ok {
+		return
+	}
+
+	responseWriter.Header().Set("Content-Type", "application/json")
+	responseWriter.WriteHeader(200)
+	json.NewEncoder(responseWriter).Encode(draftResponse(draft, plan))
+}
+
+func (apiCfg *apiConfig) updateDraftHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	userData, _ := userFromContext(req.Context())
+
+	draft, ok := apiCfg.draftFromPath(responseWriter, req, userData.ID)
+	if !ok {
+		return
+	}
+	content, ok := decodeDraftBody(responseWriter, req)
+	if !ok {
+		return
+	}
+	plan, ok := apiCfg.requestPlan(responseWriter, userData.ID)
+	if !ok {
+		return
+	}
+
+	updated, err := apiCfg.db.UpdateDraft(context.Background(), database.UpdateDraftParams{
+		Body:           content.Body,
+		ContentWarning: content.ContentWarning,
+		Sensitive:      content.Sensitive,
+		UpdatedAt:      time.Now(),
+		ID:             draft.ID,
+		UserID:         userData.ID,
+	})
+	if err != nil {
+		responseWriter.WriteHeader(404)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("No such draft " + draft.ID.String()))
+		return
+	}
+
+	responseWriter.Header().Set("Content-Type", "application/json")
+	responseWriter.WriteHeader(200)
+	json.NewEncoder(responseWriter).Encode(draftResponse(updated, plan))
+}
+
+func (apiCfg *apiConfig) deleteDraftHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	userData, _ := userFromContext(req.Context())
+
+	draft, ok := apiCfg.draftFromPath(responseWriter, req, userData.ID)
+	if !ok {
+		return
+	}
+
+	_, err := apiCfg.db.DeleteDraft(context.Background(), database.DeleteDraftParams{
+		ID:     draft.ID,
+		UserID: userData.ID,
+	})
+	if err != nil {
+		responseWriter.WriteHeader(404)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("No such draft " + draft.ID.String()))
+		return
+	}
+	responseWriter.WriteHeader(204)
+}
+
+// publishDraftHandler turns a draft into a chirp through the normal creation
+// path, so the plan's limits apply, and deletes the draft in the same
+// transaction as the chirp is created.
+func (apiCfg *apiConfig) publishDraftHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	type requestBody struct {
+		PublishAt      *time.Time `json:"publish_at"`
+		ContentWarning *string    `json:"content_warning"`
+		Sensitive      *bool      `json:"sensitive"`
+	}
+
+	userData, _ := userFromContext(req.Context())
+
+	draft, ok := apiCfg.draftFromPath(responseWriter, req, userData.ID)
+	if !ok {
+		return
+	}
+
+	// The body is optional, an empty one publishes straight away.
+	decoder := json.NewDecoder(req.Body)
+	decoder.DisallowUnknownFields()
+	requestData := requestBody{}
+	if err := decoder.Decode(&requestData); err != nil && !errors.Is(err, io.EOF) {
+		responseWriter.WriteHeader(406)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Json Decode failed."))
+		return
+	}
+
+	plan, ok := apiCfg.requestPlan(responseWriter, userData.ID)
+	if !ok {
+		return
+	}
+
+	// The draft's content warning and sensitive flag apply unless the
+	// request overrides them.
+	chirp := newChirp{
+		Body:           draft.Body,
+		PublishAt:      requestData.PublishAt,
+		ContentWarning: draft.ContentWarning,
+		Sensitive:      draft.Sensitive,
+	}
+	if requestData.ContentWarning != nil {
+		chirp.ContentWarning = *requestData.ContentWarning
+	}
+	if requestData.Sensitive != nil {
+		chirp.Sensitive = *requestData.Sensitive
+	}
+
+	responseData, ok := apiCfg.createChirp(responseWriter, userData.ID, plan, chirp, func(q *database.Queries) error {
+		_, err := q.DeleteDraft(context.Background(), database.DeleteDraftParams{
+			ID:     draft.ID,
+			UserID: userData.ID,
+		})
+		// A concurrent publish got there first.
+		if errors.Is(err, sql.ErrNoRows) {
+			return notFoundError{message: "No such draft " + draft.ID.String()}
+		}
+		return err
+	})
+	if !ok {
+		return
+	}
+
+	responseWriter.Header().Set("Content-Type", "application/json")
+	responseWriter.WriteHeader(201)
+	json.NewEncoder(responseWriter).Encode(responseData)
+}
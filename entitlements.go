@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/anantashahane/Chirpy/internal/entitlements"
+	"github.com/google/uuid"
+)
+
+// planFor works out the user's current plan from their subscription.
+// users.is_chirpy_red is only cleared by the expiry job every 15 minutes, so
+// it may still be set for a subscription that has just lapsed.
+func (apiCfg *apiConfig) planFor(ctx context.Context, userID uuid.UUID) (entitlements.Plan, error) {
+	subscription, err := apiCfg.db.GetSubscription(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return entitlements.ForUser(false), nil
+	}
+	if err != nil {
+		return entitlements.Plan{}, err
+	}
+	return entitlements.ForUser(subscriptionIsRed(subscription, time.Now())), nil
+}
+
+// requestPlan is planFor for handlers, answering 500 itself when the plan
+// can't be loaded.
+func (apiCfg *apiConfig) requestPlan(responseWriter http.ResponseWriter, userID uuid.UUID) (entitlements.Plan, bool) {
+	plan, err := apiCfg.planFor(context.Background(), userID)
+	if err != nil {
+		responseWriter.WriteHeader(500)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Internal Server failed to access database."))
+		return entitlements.Plan{}, false
+	}
+	return plan, true
+}
+
+// entitlementErrorWriter answers 402 when Chirpy Red would grant the missing
+// entitlement and 403 otherwise, naming the entitlement either way.
+func entitlementErrorWriter(responseWriter http.ResponseWriter, err error) {
+	type responseBody struct {
+		Error       string `json:"error"`
+		Entitlement string `json:"entitlement"`
+		Plan        string `json:"plan"`
+	}
+
+	missing := &entitlements.MissingError{}
+	if !errors.As(err, &missing) {
+		responseWriter.WriteHeader(500)
+		return
+	}
+	code := 403
+	if missing.UpgradeAvailable {
+		code = 402
+	}
+	responseWriter.Header().Set("Content-Type", "application/json")
+	responseWriter.WriteHeader(code)
+	json.NewEncoder(responseWriter).Encode(responseBody{
+		Error:       missing.Error(),
+		Entitlement: string(missing.Entitlement),
+		Plan:        missing.Plan,
+	})
+}
+
+// allowChirpWrite applies the plan's hourly chirp limit, answering 429 when
+// it is used up.
+func (apiCfg *apiConfig) allowChirpWrite(responseWriter http.ResponseWriter, plan entitlements.Plan, userID uuid.UUID) bool {
+	ok, retryAfter := apiCfg.chirpLimiter.Allow(userID.String(), plan.ChirpsPerHour, time.Now())
+	if ok {
+		return true
+	}
+
+	message := fmt.Sprintf("Rate limit of %d chirps per hour reached.", plan.ChirpsPerHour)
+	if !plan.Has(entitlements.HigherRateLimits) {
+		message += fmt.Sprintf(" Chirpy Red includes %s.", entitlements.HigherRateLimits)
+	}
+	responseWriter.Header().Set("Retry-After", fmt.Sprint(int(math.Ceil(retryAfter.Seconds()))))
+	responseWriter.Header().Set("Content-Type", "plain/text")
+	responseWriter.WriteHeader(429)
+	responseWriter.Write([]byte(message))
+	return false
+}
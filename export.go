@@ -0,0 +1,441 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/anantashahane/Chirpy/internal/database"
+	"github.com/anantashahane/Chirpy/internal/jobs"
+	"github.com/google/uuid"
+)
+
+type dataExportResponseBody struct {
+	ID        string `json:"id"`
+	CreatedAt string `json:"created_at"`
+	Status    string `json:"status"`
+	ExpiresAt string `json:"expires_at,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+var chirpsPageTemplate = template.Must(template.New("chirps").Parse(`<!DOCTYPE html>
+<html>
+	<head>
+		<meta charset="utf-8">
+		<title>Chirps by {{.Email}}</title>
+	</head>
+	<body>
+		<h1>Chirps by {{.Email}}</h1>
+		<p>Exported {{.ExportedAt}}, {{len .Chirps}} chirp(s).</p>
+		{{range .Chirps}}
+		<article>
+			<p>{{.Body}}</p>
+			<small>{{.CreatedAt}}</small>
+		</article>
+		<hr>
+		{{end}}
+	</body>
+</html>
+`))
+
+func dataExportResponse(export database.DataExport) dataExportResponseBody {
+	responseData := dataExportResponseBody{
+		ID:        export.ID.String(),
+		CreatedAt: export.CreatedAt.String(),
+		Status:    export.Status,
+		Error:     export.Error.String,
+	}
+	if export.ExpiresAt.Valid {
+		responseData.ExpiresAt = export.ExpiresAt.Time.String()
+	}
+	return responseData
+}
+
+func (apiCfg *apiConfig) requestDataExportHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	userData, _ := userFromContext(req.Context())
+
+	var export database.DataExport
+	err := apiCfg.inTx(context.Background(), func(q *database.Queries) error {
+		var err error
+		export, err = q.CreateDataExport(context.Background(), database.CreateDataExportParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+			UserID:    userData.ID,
+			// An export that never finishes is still cleaned up.
+			ExpiresAt: sql.NullTime{Time: time.Now().Add(apiCfg.exportTTL), Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+		_, err = jobs.Enqueue(context.Background(), q, jobBuildDataExport, dataExportJob{
+			ExportID: export.ID,
+			UserID:   userData.ID,
+		}, jobs.Options{})
+		return err
+	})
+	if err != nil {
+		responseWriter.WriteHeader(503)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Unable to start export."))
+		return
+	}
+
+	responseWriter.Header().Set("Content-Type", "application/json")
+	responseWriter.Header().Set("Location", "/api/users/me/export/"+export.ID.String())
+	responseWriter.WriteHeader(202)
+	json.NewEncoder(responseWriter).Encode(dataExportResponse(export))
+}
+
+func (apiCfg *apiConfig) getDataExportHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	userData, _ := userFromContext(req.Context())
+
+	exportID, err := uuid.Parse(req.PathValue("exportID"))
+	if err != nil {
+		responseWriter.WriteHeader(404)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Error parsing export ID."))
+		return
+	}
+
+	export, err := apiCfg.db.GetDataExport(context.Background(), database.GetDataExportParams{
+		ID:     exportID,
+		UserID: userData.ID,
+	})
+	if err != nil {
+		responseWriter.WriteHeader(404)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("No such export " + exportID.String()))
+		return
+	}
+
+	if export.ExpiresAt.Valid && export.ExpiresAt.Time.Before(time.Now()) {
+		responseWriter.WriteHeader(410)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Export expired, request a new one."))
+		return
+	}
+
+	if export.Status != "ready" {
+		code := 202
+		if export.Status == "failed" {
+			code = 500
+		}
+		responseWriter.Header().Set("Content-Type", "application/json")
+		responseWriter.WriteHeader(code)
+		json.NewEncoder(responseWriter).Encode(dataExportResponse(export))
+		return
+	}
+
+	responseWriter.Header().Set("Content-Type", "application/zip")
+	responseWriter.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"chirpy-export-%s.zip\"", export.ID))
+	http.ServeFile(responseWriter, req, export.FilePath.String)
+}
+
+type dataExportJob struct {
+	ExportID uuid.UUID `json:"export_id"`
+	UserID   uuid.UUID `json:"user_id"`
+}
+
+func (cfg *apiConfig) buildDataExportJob(ctx context.Context, job dataExportJob) error {
+	export, err := cfg.db.GetDataExport(ctx, database.GetDataExportParams{
+		ID:     job.ExportID,
+		UserID: job.UserID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return jobs.Permanent(err)
+	}
+	if err != nil {
+		return err
+	}
+	if export.Status != "pending" {
+		return nil
+	}
+	userData, err := cfg.db.GetUserByID(ctx, job.UserID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return cfg.failDataExport(ctx, export, jobs.Permanent(err))
+	}
+	if err != nil {
+		return err
+	}
+	return cfg.buildDataExport(ctx, export, userData)
+}
+
+// buildDataExport writes the user's data into a ZIP archive under the export
+// directory and marks the export ready. Errors are returned so the job is
+// retried; a retry writes the archive again over the same path.
+func (apiCfg *apiConfig) buildDataExport(ctx context.Context, export database.DataExport, userData database.User) error {
+	path := filepath.Join(apiCfg.exportDir, export.ID.String()+".zip")
+	err := apiCfg.writeDataExport(ctx, path, userData)
+	if err != nil {
+		os.Remove(path)
+		fmt.Println("Data export " + export.ID.String() + " failed: " + err.Error())
+		return err
+	}
+
+	_, err = apiCfg.db.CompleteDataExport(ctx, database.CompleteDataExportParams{
+		FilePath:  sql.NullString{String: path, Valid: true},
+		ExpiresAt: sql.NullTime{Time: time.Now().Add(apiCfg.exportTTL), Valid: true},
+		UpdatedAt: time.Now(),
+		ID:        export.ID,
+	})
+	if err != nil {
+		fmt.Println("Data export " + export.ID.String() + " could not be marked ready: " + err.Error())
+	}
+	return err
+}
+
+// failDataExport marks the export failed with cause, which it returns so the
+// job stops too. The row expires like a finished export would.
+func (cfg *apiConfig) failDataExport(ctx context.Context, export database.DataExport, cause error) error {
+	err := cfg.db.FailDataExport(ctx, database.FailDataExportParams{
+		Error:     sql.NullString{String: cause.Error(), Valid: true},
+		ExpiresAt: sql.NullTime{Time: time.Now().Add(cfg.exportTTL), Valid: true},
+		UpdatedAt: time.Now(),
+		ID:        export.ID,
+	})
+	if err != nil {
+		return err
+	}
+	return cause
+}
+
+func (apiCfg *apiConfig) writeDataExport(ctx context.Context, path string, userData database.User) error {
+	type profile struct {
+		ID        string `json:"id"`
+		CreatedAt string `json:"created_at"`
+		UpdatedAt string `json:"updated_at"`
+		Email     string `json:"email"`
+		Role      string `json:"role"`
+		IsRed     bool   `json:"is_chirpy_red"`
+	}
+	type session struct {
+		CreatedAt string `json:"created_at"`
+		ExpiresAt string `json:"expires_at"`
+		RevokedAt string `json:"revoked_at,omitempty"`
+	}
+	type identity struct {
+		Issuer    string `json:"issuer"`
+		Subject   string `json:"subject"`
+		Email     string `json:"email"`
+		CreatedAt string `json:"created_at"`
+	}
+	type list struct {
+		listResponseBody
+		Members []relationResponseBody `json:"members"`
+	}
+
+	chirps, err := apiCfg.db.GetChirpsByAuthor(ctx, userData.ID)
+	if err != nil {
+		return err
+	}
+	tokens, err := apiCfg.db.GetUserRefreshTokens(ctx, userData.ID)
+	if err != nil {
+		return err
+	}
+	identities, err := apiCfg.db.GetUserIdentitiesForUser(ctx, userData.ID)
+	if err != nil {
+		return err
+	}
+	drafts, err := apiCfg.db.ListDrafts(ctx, userData.ID)
+	if err != nil {
+		return err
+	}
+	bookmarks, err := apiCfg.db.GetBookmarks(ctx, database.GetBookmarksParams{
+		UserID:           userData.ID,
+		Before:           firstPage.Time,
+		BeforeID:         firstPage.ID,
+		IncludeSensitive: true,
+		MaxResults:       math.MaxInt32,
+	})
+	if err != nil {
+		return err
+	}
+	messages, err := apiCfg.db.GetMessagesBySender(ctx, userData.ID)
+	if err != nil {
+		return err
+	}
+	lists, err := apiCfg.db.GetListsByOwner(ctx, database.GetListsByOwnerParams{
+		OwnerID:        userData.ID,
+		IncludePrivate: true,
+	})
+	if err != nil {
+		return err
+	}
+	blocks, err := apiCfg.db.ListBlocks(ctx, userData.ID)
+	if err != nil {
+		return err
+	}
+	mutes, err := apiCfg.db.ListMutes(ctx, userData.ID)
+	if err != nil {
+		return err
+	}
+
+	chirpData := []chirpResponseBody{}
+	for _, chirp := range chirps {
+		chirpData = append(chirpData, chirpResponse(chirp))
+	}
+	// Refresh token values are credentials, only their lifetimes are exported.
+	sessions := []session{}
+	for _, token := range tokens {
+		sessions = append(sessions, session{
+			CreatedAt: token.CreatedAt.String(),
+			ExpiresAt: token.ExpiresAt.String(),
+		})
+		if token.RevokedAt.Valid {
+			sessions[len(sessions)-1].RevokedAt = token.RevokedAt.Time.String()
+		}
+	}
+	identityData := []identity{}
+	for _, linked := range identities {
+		identityData = append(identityData, identity{
+			Issuer:    linked.Issuer,
+			Subject:   linked.Subject,
+			Email:     linked.Email,
+			CreatedAt: linked.CreatedAt.String(),
+		})
+	}
+	plan, err := apiCfg.planFor(ctx, userData.ID)
+	if err != nil {
+		return err
+	}
+	draftData := []draftResponseBody{}
+	for _, draft := range drafts {
+		draftData = append(draftData, draftResponse(draft, plan))
+	}
+	bookmarkData := []bookmarkResponseBody{}
+	for _, bookmark := range bookmarks {
+		bookmarkData = append(bookmarkData, bookmarkResponseBody{
+			chirpResponseBody: chirpResponse(bookmark.Chirp),
+			BookmarkedAt:      bookmark.BookmarkedAt.String(),
+		})
+	}
+	// Only messages the user sent are theirs to export, not their
+	// conversation partners' replies.
+	messageData := []messageResponseBody{}
+	for _, message := range messages {
+		messageData = append(messageData, messageResponse(message))
+	}
+	listData := []list{}
+	for _, owned := range lists {
+		members, err := apiCfg.db.GetListMembers(ctx, owned.ID)
+		if err != nil {
+			return err
+		}
+		memberData := []relationResponseBody{}
+		for _, member := range members {
+			memberData = append(memberData, relationResponseBody{
+				UserID:    member.UserID.String(),
+				CreatedAt: member.AddedAt.String(),
+			})
+		}
+		listData = append(listData, list{listResponseBody: listResponse(owned), Members: memberData})
+	}
+	blockData := []relationResponseBody{}
+	for _, block := range blocks {
+		blockData = append(blockData, relationResponseBody{
+			UserID:    block.BlockedID.String(),
+			CreatedAt: block.CreatedAt.String(),
+		})
+	}
+	muteData := []relationResponseBody{}
+	for _, mute := range mutes {
+		muteData = append(muteData, relationResponseBody{
+			UserID:    mute.MutedID.String(),
+			CreatedAt: mute.CreatedAt.String(),
+		})
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	archive := zip.NewWriter(file)
+
+	jsonFiles := []struct {
+		name string
+		data any
+	}{
+		{name: "profile.json", data: profile{
+			ID:        userData.ID.String(),
+			CreatedAt: userData.CreatedAt.String(),
+			UpdatedAt: userData.UpdatedAt.String(),
+			Email:     userData.Email,
+			Role:      userData.Role,
+			IsRed:     userData.IsChirpyRed.Bool,
+		}},
+		{name: "chirps.json", data: chirpData},
+		{name: "drafts.json", data: draftData},
+		{name: "bookmarks.json", data: bookmarkData},
+		{name: "messages.json", data: messageData},
+		{name: "lists.json", data: listData},
+		{name: "blocks.json", data: blockData},
+		{name: "mutes.json", data: muteData},
+		{name: "sessions.json", data: sessions},
+		{name: "identities.json", data: identityData},
+	}
+	for _, jsonFile := range jsonFiles {
+		writer, err := archive.Create(jsonFile.name)
+		if err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(writer)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(jsonFile.data); err != nil {
+			return err
+		}
+	}
+
+	writer, err := archive.Create("chirps.html")
+	if err != nil {
+		return err
+	}
+	err = chirpsPageTemplate.Execute(writer, struct {
+		Email      string
+		ExportedAt string
+		Chirps     []chirpResponseBody
+	}{Email: userData.Email, ExportedAt: time.Now().String(), Chirps: chirpData})
+	if err != nil {
+		return err
+	}
+
+	if err := archive.Close(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// purgeExpiredExports removes expired export archives and their rows.
+func (cfg *apiConfig) purgeExpiredExports(ctx context.Context) {
+	expired, err := cfg.db.DeleteExpiredDataExports(ctx, sql.NullTime{Time: time.Now(), Valid: true})
+	if err != nil {
+		fmt.Println("Export purge failed: " + err.Error())
+		return
+	}
+	for _, export := range expired {
+		cfg.removeExportFile(export)
+	}
+}
+
+// removeExportFile deletes an export's archive, including one still being
+// written when the export was deleted.
+func (cfg *apiConfig) removeExportFile(export database.DataExport) {
+	path := filepath.Join(cfg.exportDir, export.ID.String()+".zip")
+	if export.FilePath.Valid {
+		path = export.FilePath.String
+	}
+	os.Remove(path)
+}
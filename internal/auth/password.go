@@ -0,0 +1,165 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Supported password hashing algorithms.
+const (
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+)
+
+// PasswordPolicy decides how new password hashes are made. Stored hashes
+// carry their own algorithm and parameters, so older hashes keep verifying
+// after the policy changes.
+type PasswordPolicy struct {
+	Algorithm string
+
+	BcryptCost int
+
+	Argon2Time      uint32
+	Argon2MemoryKiB uint32
+	Argon2Threads   uint8
+	Argon2KeyLength uint32
+}
+
+const argon2SaltLength = 16
+
+var passwordPolicy = DefaultPasswordPolicy()
+
+func DefaultPasswordPolicy() PasswordPolicy {
+	return PasswordPolicy{
+		Algorithm:       AlgorithmBcrypt,
+		BcryptCost:      bcrypt.DefaultCost,
+		Argon2Time:      3,
+		Argon2MemoryKiB: 64 * 1024,
+		Argon2Threads:   2,
+		Argon2KeyLength: 32,
+	}
+}
+
+// SetPasswordPolicy replaces the policy used by HashPassword and
+// PasswordNeedsRehash.
+func SetPasswordPolicy(policy PasswordPolicy) error {
+	switch policy.Algorithm {
+	case AlgorithmBcrypt:
+		if policy.BcryptCost < bcrypt.MinCost || policy.BcryptCost > bcrypt.MaxCost {
+			return fmt.Errorf("bcrypt cost must be between %d and %d, got %d", bcrypt.MinCost, bcrypt.MaxCost, policy.BcryptCost)
+		}
+	case AlgorithmArgon2id:
+		if policy.Argon2Time < 1 || policy.Argon2MemoryKiB < 8*uint32(policy.Argon2Threads) || policy.Argon2Threads < 1 || policy.Argon2KeyLength < 16 {
+			return fmt.Errorf("argon2id parameters too weak: t=%d m=%d p=%d keylen=%d", policy.Argon2Time, policy.Argon2MemoryKiB, policy.Argon2Threads, policy.Argon2KeyLength)
+		}
+	default:
+		return fmt.Errorf("unknown password hashing algorithm %q", policy.Algorithm)
+	}
+	passwordPolicy = policy
+	return nil
+}
+
+func HashPassword(password string) (string, error) {
+	if passwordPolicy.Algorithm == AlgorithmArgon2id {
+		return hashArgon2id(password, passwordPolicy)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), passwordPolicy.BcryptCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+func PasswordMatchesHash(password, hash string) bool {
+	if strings.HasPrefix(hash, "$"+AlgorithmArgon2id+"$") {
+		params, salt, key, err := decodeArgon2id(hash)
+		if err != nil {
+			return false
+		}
+		tried := argon2.IDKey([]byte(password), salt, params.Argon2Time, params.Argon2MemoryKiB, params.Argon2Threads, uint32(len(key)))
+		return subtle.ConstantTimeCompare(tried, key) == 1
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)); err != nil {
+		return false
+	}
+	return true
+}
+
+// PasswordNeedsRehash reports whether hash was made with another algorithm or
+// weaker parameters than the current policy.
+func PasswordNeedsRehash(hash string) bool {
+	if strings.HasPrefix(hash, "$"+AlgorithmArgon2id+"$") {
+		if passwordPolicy.Algorithm != AlgorithmArgon2id {
+			return true
+		}
+		params, _, key, err := decodeArgon2id(hash)
+		if err != nil {
+			return true
+		}
+		return params.Argon2Time < passwordPolicy.Argon2Time ||
+			params.Argon2MemoryKiB < passwordPolicy.Argon2MemoryKiB ||
+			params.Argon2Threads < passwordPolicy.Argon2Threads ||
+			uint32(len(key)) < passwordPolicy.Argon2KeyLength
+	}
+
+	if passwordPolicy.Algorithm != AlgorithmBcrypt {
+		return true
+	}
+	cost, err := bcrypt.Cost([]byte(hash))
+	if err != nil {
+		return true
+	}
+	return cost < passwordPolicy.BcryptCost
+}
+
+// hashArgon2id encodes the hash in the PHC string format,
+// $argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>.
+func hashArgon2id(password string, policy PasswordPolicy) (string, error) {
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, policy.Argon2Time, policy.Argon2MemoryKiB, policy.Argon2Threads, policy.Argon2KeyLength)
+	return fmt.Sprintf("$%s$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		AlgorithmArgon2id,
+		argon2.Version,
+		policy.Argon2MemoryKiB,
+		policy.Argon2Time,
+		policy.Argon2Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func decodeArgon2id(hash string) (PasswordPolicy, []byte, []byte, error) {
+	params := PasswordPolicy{Algorithm: AlgorithmArgon2id}
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != AlgorithmArgon2id {
+		return params, nil, nil, fmt.Errorf("malformed argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("unsupported argon2id version %q", parts[2])
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Argon2MemoryKiB, &params.Argon2Time, &params.Argon2Threads); err != nil {
+		return params, nil, nil, fmt.Errorf("malformed argon2id parameters: %w", err)
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, err
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return params, nil, nil, err
+	}
+	params.Argon2KeyLength = uint32(len(key))
+	return params, salt, key, nil
+}
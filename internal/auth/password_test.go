@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func usePolicy(t *testing.T, policy PasswordPolicy) {
+	previous := passwordPolicy
+	if err := SetPasswordPolicy(policy); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { passwordPolicy = previous })
+}
+
+func fastArgon2Policy() PasswordPolicy {
+	policy := DefaultPasswordPolicy()
+	policy.Algorithm = AlgorithmArgon2id
+	policy.Argon2Time = 1
+	policy.Argon2MemoryKiB = 1024
+	policy.Argon2Threads = 1
+	return policy
+}
+
+func TestArgon2idHashing(t *testing.T) {
+	usePolicy(t, fastArgon2Policy())
+
+	hash, err := HashPassword("iAmB4Tm@n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Errorf("hash not in PHC format: %s", hash)
+	}
+	if !PasswordMatchesHash("iAmB4Tm@n", hash) {
+		t.Errorf("correct password rejected")
+	}
+	if PasswordMatchesHash("meaw", hash) {
+		t.Errorf("wrong password accepted")
+	}
+	if PasswordNeedsRehash(hash) {
+		t.Errorf("hash made with the current policy should not need a rehash")
+	}
+}
+
+func TestPasswordNeedsRehash(t *testing.T) {
+	weakBcrypt, err := bcrypt.GenerateFromPassword([]byte("pw"), 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bcryptPolicy := DefaultPasswordPolicy()
+	bcryptPolicy.BcryptCost = 10
+	usePolicy(t, bcryptPolicy)
+	if !PasswordNeedsRehash(string(weakBcrypt)) {
+		t.Errorf("cost 8 bcrypt hash should need a rehash under cost 10")
+	}
+	if !PasswordMatchesHash("pw", string(weakBcrypt)) {
+		t.Errorf("old bcrypt hash should still verify")
+	}
+
+	usePolicy(t, fastArgon2Policy())
+	if !PasswordNeedsRehash(string(weakBcrypt)) {
+		t.Errorf("bcrypt hash should need a rehash under argon2id")
+	}
+	argonHash, err := HashPassword("pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stronger := fastArgon2Policy()
+	stronger.Argon2MemoryKiB = 2048
+	usePolicy(t, stronger)
+	if !PasswordNeedsRehash(argonHash) {
+		t.Errorf("argon2id hash with less memory should need a rehash")
+	}
+	if !PasswordMatchesHash("pw", argonHash) {
+		t.Errorf("old argon2id hash should still verify")
+	}
+}
+
+func TestSetPasswordPolicyRejects(t *testing.T) {
+	policies := map[string]PasswordPolicy{
+		"unknown algorithm": {Algorithm: "md5"},
+		"bcrypt cost":       {Algorithm: AlgorithmBcrypt, BcryptCost: 2},
+		"argon2 threads":    {Algorithm: AlgorithmArgon2id, Argon2Time: 1, Argon2MemoryKiB: 1024, Argon2KeyLength: 32},
+	}
+	for name, policy := range policies {
+		if err := SetPasswordPolicy(policy); err == nil {
+			t.Errorf("%s: policy should have been rejected", name)
+		}
+	}
+}
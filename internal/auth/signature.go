@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SignPayload is the hex HMAC-SHA256 of "<unix timestamp>.<body>".
+func SignPayload(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature accepts the payload if any signature matches any secret,
+// so secrets can be rotated by briefly configuring both. Timestamps further
+// than tolerance from now are rejected to stop replayed deliveries.
+func VerifySignature(timestamp string, signatures []string, body []byte, secrets []string, tolerance time.Duration, now time.Time) error {
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("Malformed signature timestamp %q.", timestamp)
+	}
+	skew := now.Sub(time.Unix(unix, 0))
+	if skew > tolerance || skew < -tolerance {
+		return fmt.Errorf("Signature timestamp outside the %s tolerance window.", tolerance)
+	}
+
+	for _, secret := range secrets {
+		expected := []byte(SignPayload(secret, unix, body))
+		for _, signature := range signatures {
+			if hmac.Equal(expected, []byte(signature)) {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("No matching signature.")
+}
+
+// GetPolkaSignature reads the X-Polka-Timestamp header and the v1 signatures
+// from X-Polka-Signature, formatted "v1=<hex>[,v1=<hex>...]".
+func GetPolkaSignature(headers http.Header) (string, []string, error) {
+	timestamp := headers.Get("X-Polka-Timestamp")
+	if timestamp == "" {
+		return "", nil, fmt.Errorf("X-Polka-Timestamp not found in http header.")
+	}
+	signatures := []string{}
+	for _, part := range strings.Split(headers.Get("X-Polka-Signature"), ",") {
+		version, signature, found := strings.Cut(strings.TrimSpace(part), "=")
+		if found && version == "v1" && signature != "" {
+			signatures = append(signatures, signature)
+		}
+	}
+	if len(signatures) == 0 {
+		return "", nil, fmt.Errorf("v1 signature not found in X-Polka-Signature header.")
+	}
+	return timestamp, signatures, nil
+}
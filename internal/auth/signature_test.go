@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestVerifySignature(t *testing.T) {
+	body := []byte(`{"event":"user.upgraded","data":{"user_id":"3311741c-680c-4546-99f3-fc9efac2036c"}}`)
+	now := time.Now()
+	signedAt := now.Add(-time.Minute).Unix()
+	timestamp := strconv.FormatInt(signedAt, 10)
+	signature := SignPayload("old-secret", signedAt, body)
+
+	tests := []struct {
+		name      string
+		timestamp string
+		body      []byte
+		secrets   []string
+		valid     bool
+	}{
+		{name: "current secret", timestamp: timestamp, body: body, secrets: []string{"old-secret"}, valid: true},
+		{name: "during rotation", timestamp: timestamp, body: body, secrets: []string{"new-secret", "old-secret"}, valid: true},
+		{name: "after rotation", timestamp: timestamp, body: body, secrets: []string{"new-secret"}, valid: false},
+		{name: "tampered body", timestamp: timestamp, body: []byte(`{"event":"user.upgraded"}`), secrets: []string{"old-secret"}, valid: false},
+		{name: "tampered timestamp", timestamp: strconv.FormatInt(signedAt+1, 10), body: body, secrets: []string{"old-secret"}, valid: false},
+		{name: "malformed timestamp", timestamp: "yesterday", body: body, secrets: []string{"old-secret"}, valid: false},
+	}
+	for _, test := range tests {
+		err := VerifySignature(test.timestamp, []string{signature}, test.body, test.secrets, 5*time.Minute, now)
+		if (err == nil) != test.valid {
+			t.Errorf("%s: expected valid=%v, got %v", test.name, test.valid, err)
+		}
+	}
+
+	replayed := now.Add(-time.Hour).Unix()
+	err := VerifySignature(strconv.FormatInt(replayed, 10), []string{SignPayload("old-secret", replayed, body)}, body, []string{"old-secret"}, 5*time.Minute, now)
+	if err == nil {
+		t.Errorf("delivery signed an hour ago should be rejected as a replay")
+	}
+}
+
+func TestGetPolkaSignature(t *testing.T) {
+	header := http.Header{}
+	header.Set("X-Polka-Timestamp", "1700000000")
+	header.Set("X-Polka-Signature", "v0=ignored, v1=abc,v1=def")
+	timestamp, signatures, err := GetPolkaSignature(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if timestamp != "1700000000" || len(signatures) != 2 || signatures[0] != "abc" || signatures[1] != "def" {
+		t.Errorf("unexpected parse %s %v", timestamp, signatures)
+	}
+
+	header.Set("X-Polka-Signature", "v0=only-old")
+	if _, _, err := GetPolkaSignature(header); err == nil {
+		t.Errorf("header without v1 signatures should be rejected")
+	}
+}
@@ -7,28 +7,93 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+const cancelScheduledChirp = `-- name: CancelScheduledChirp :one
+DELETE FROM chirps
+WHERE id = $1 AND user_id = $2 AND NOT published
+RETURNING id, created_at, updated_at, body, user_id, published, publish_at, deleted_at, pinned_position, visibility, content_warning, sensitive
+`
+
+type CancelScheduledChirpParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) CancelScheduledChirp(ctx context.Context, arg CancelScheduledChirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, cancelScheduledChirp, arg.ID, arg.UserID)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.Published,
+		&i.PublishAt,
+		&i.DeletedAt,
+		&i.PinnedPosition,
+		&i.Visibility,
+		&i.ContentWarning,
+		&i.Sensitive,
+	)
+	return i, err
+}
+
+const clearPinnedChirps = `-- name: ClearPinnedChirps :exec
+UPDATE chirps
+SET pinned_position = NULL
+WHERE user_id = $1 AND pinned_position IS NOT NULL
+`
+
+func (q *Queries) ClearPinnedChirps(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, clearPinnedChirps, userID)
+	return err
+}
+
+const countPinnedChirps = `-- name: CountPinnedChirps :one
+SELECT COUNT(*) FROM chirps
+WHERE user_id = $1 AND pinned_position IS NOT NULL
+`
+
+func (q *Queries) CountPinnedChirps(ctx context.Context, userID uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countPinnedChirps, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createChirps = `-- name: CreateChirps :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, published, publish_at, visibility, content_warning, sensitive)
 VALUES (
     $1,
     $2,
     $3,
     $4,
-    $5
-) RETURNING id, created_at, updated_at, body, user_id
+    $5,
+    $6,
+    $7,
+    $8,
+    $9,
+    $10
+) RETURNING id, created_at, updated_at, body, user_id, published, publish_at, deleted_at, pinned_position, visibility, content_warning, sensitive
 `
 
 type CreateChirpsParams struct {
-	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	Body      string
-	UserID    uuid.UUID
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	Body           string
+	UserID         uuid.UUID
+	Published      bool
+	PublishAt      sql.NullTime
+	Visibility     string
+	ContentWarning string
+	Sensitive      bool
 }
 
 func (q *Queries) CreateChirps(ctx context.Context, arg CreateChirpsParams) (Chirp, error) {
@@ -38,6 +103,11 @@ func (q *Queries) CreateChirps(ctx context.Context, arg CreateChirpsParams) (Chi
 		arg.UpdatedAt,
 		arg.Body,
 		arg.UserID,
+		arg.Published,
+		arg.PublishAt,
+		arg.Visibility,
+		arg.ContentWarning,
+		arg.Sensitive,
 	)
 	var i Chirp
 	err := row.Scan(
@@ -46,18 +116,37 @@ func (q *Queries) CreateChirps(ctx context.Context, arg CreateChirpsParams) (Chi
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.Published,
+		&i.PublishAt,
+		&i.DeletedAt,
+		&i.PinnedPosition,
+		&i.Visibility,
+		&i.ContentWarning,
+		&i.Sensitive,
 	)
 	return i, err
 }
 
-const deleteChirpByID = `-- name: DeleteChirpByID :one
-DELETE FROM chirps
-WHERE id = $1
-RETURNING id, created_at, updated_at, body, user_id
+const getChirpByID = `-- name: GetChirpByID :one
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.published, chirps.publish_at, chirps.deleted_at, chirps.pinned_position, chirps.visibility, chirps.content_warning, chirps.sensitive FROM chirps
+JOIN users ON users.id = chirps.user_id
+WHERE chirps.id = $1 AND users.deletion_requested_at IS NULL AND chirps.deleted_at IS NULL
+AND (chirps.published OR chirps.user_id = $2)
+AND (chirps.visibility <> 'followers' OR chirps.user_id = $2)
+AND NOT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocks.blocker_id = $2 AND blocks.blocked_id = chirps.user_id)
+    OR (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = $2)
+)
 `
 
-func (q *Queries) DeleteChirpByID(ctx context.Context, id uuid.UUID) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, deleteChirpByID, id)
+type GetChirpByIDParams struct {
+	ID       uuid.UUID
+	ViewerID uuid.UUID
+}
+
+func (q *Queries) GetChirpByID(ctx context.Context, arg GetChirpByIDParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getChirpByID, arg.ID, arg.ViewerID)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -65,35 +154,265 @@ func (q *Queries) DeleteChirpByID(ctx context.Context, id uuid.UUID) (Chirp, err
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.Published,
+		&i.PublishAt,
+		&i.DeletedAt,
+		&i.PinnedPosition,
+		&i.Visibility,
+		&i.ContentWarning,
+		&i.Sensitive,
 	)
 	return i, err
 }
 
-const getChirpByID = `-- name: GetChirpByID :one
-SELECT id, created_at, updated_at, body, user_id FROM chirps
-WHERE id = $1
+const getChirps = `-- name: GetChirps :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.published, chirps.publish_at, chirps.deleted_at, chirps.pinned_position, chirps.visibility, chirps.content_warning, chirps.sensitive FROM chirps
+JOIN users ON users.id = chirps.user_id
+WHERE users.deletion_requested_at IS NULL AND chirps.published AND chirps.deleted_at IS NULL
+AND (chirps.visibility = 'public' OR chirps.user_id = $1)
+AND ($2::boolean OR NOT (chirps.sensitive OR chirps.content_warning <> '') OR chirps.user_id = $1)
+AND NOT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocks.blocker_id = $1 AND blocks.blocked_id = chirps.user_id)
+    OR (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = $1)
+)
+AND NOT EXISTS (
+    SELECT 1 FROM mutes
+    WHERE mutes.muter_id = $1 AND mutes.muted_id = chirps.user_id
+)
+ORDER BY chirps.created_at
 `
 
-func (q *Queries) GetChirpByID(ctx context.Context, id uuid.UUID) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, getChirpByID, id)
-	var i Chirp
+type GetChirpsParams struct {
+	ViewerID         uuid.UUID
+	IncludeSensitive bool
+}
+
+func (q *Queries) GetChirps(ctx context.Context, arg GetChirpsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirps, arg.ViewerID, arg.IncludeSensitive)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.Published,
+			&i.PublishAt,
+			&i.DeletedAt,
+			&i.PinnedPosition,
+			&i.Visibility,
+			&i.ContentWarning,
+			&i.Sensitive,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpsByAuthor = `-- name: GetChirpsByAuthor :many
+SELECT id, created_at, updated_at, body, user_id, published, publish_at, deleted_at, pinned_position, visibility, content_warning, sensitive FROM chirps
+WHERE user_id = $1
+ORDER BY created_at
+`
+
+func (q *Queries) GetChirpsByAuthor(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsByAuthor, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.Published,
+			&i.PublishAt,
+			&i.DeletedAt,
+			&i.PinnedPosition,
+			&i.Visibility,
+			&i.ContentWarning,
+			&i.Sensitive,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpStats = `-- name: GetChirpStats :one
+SELECT
+    COUNT(*) AS total_chirps,
+    COUNT(*) FILTER (WHERE created_at > $1) AS chirps_last_7_days,
+    COUNT(*) FILTER (WHERE created_at > $2) AS chirps_last_30_days,
+    COALESCE(AVG(LENGTH(body)), 0)::float8 AS average_length,
+    COALESCE(MAX(LENGTH(body)), 0)::int AS longest_chirp
+FROM chirps
+WHERE user_id = $3 AND published AND deleted_at IS NULL
+`
+
+type GetChirpStatsParams struct {
+	WeekAgo  time.Time
+	MonthAgo time.Time
+	UserID   uuid.UUID
+}
+
+type GetChirpStatsRow struct {
+	TotalChirps      int64
+	ChirpsLast7Days  int64
+	ChirpsLast30Days int64
+	AverageLength    float64
+	LongestChirp     int32
+}
+
+func (q *Queries) GetChirpStats(ctx context.Context, arg GetChirpStatsParams) (GetChirpStatsRow, error) {
+	row := q.db.QueryRowContext(ctx, getChirpStats, arg.WeekAgo, arg.MonthAgo, arg.UserID)
+	var i GetChirpStatsRow
+	err := row.Scan(
+		&i.TotalChirps,
+		&i.ChirpsLast7Days,
+		&i.ChirpsLast30Days,
+		&i.AverageLength,
+		&i.LongestChirp,
+	)
+	return i, err
+}
+
+const getChirpTombstone = `-- name: GetChirpTombstone :one
+SELECT id, deleted_at FROM chirps
+WHERE id = $1 AND deleted_at IS NOT NULL AND published
+AND (chirps.visibility <> 'followers' OR chirps.user_id = $2)
+AND NOT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocks.blocker_id = $2 AND blocks.blocked_id = chirps.user_id)
+    OR (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = $2)
+)
+`
+
+type GetChirpTombstoneParams struct {
+	ID       uuid.UUID
+	ViewerID uuid.UUID
+}
+
+type GetChirpTombstoneRow struct {
+	ID        uuid.UUID
+	DeletedAt sql.NullTime
+}
+
+func (q *Queries) GetChirpTombstone(ctx context.Context, arg GetChirpTombstoneParams) (GetChirpTombstoneRow, error) {
+	row := q.db.QueryRowContext(ctx, getChirpTombstone, arg.ID, arg.ViewerID)
+	var i GetChirpTombstoneRow
 	err := row.Scan(
 		&i.ID,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-		&i.Body,
-		&i.UserID,
+		&i.DeletedAt,
 	)
 	return i, err
 }
 
-const getChirps = `-- name: GetChirps :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps
-ORDER BY created_at
+const getListChirps = `-- name: GetListChirps :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.published, chirps.publish_at, chirps.deleted_at, chirps.pinned_position, chirps.visibility, chirps.content_warning, chirps.sensitive FROM chirps
+JOIN list_members ON list_members.user_id = chirps.user_id
+JOIN users ON users.id = chirps.user_id
+WHERE list_members.list_id = $1 AND users.deletion_requested_at IS NULL
+AND chirps.published AND chirps.deleted_at IS NULL
+AND (chirps.visibility = 'public' OR chirps.user_id = $2)
+AND ($3::boolean OR NOT (chirps.sensitive OR chirps.content_warning <> '') OR chirps.user_id = $2)
+AND NOT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocks.blocker_id = $2 AND blocks.blocked_id = chirps.user_id)
+    OR (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = $2)
+)
+AND (chirps.created_at, chirps.id) < ($4::timestamp, $5::uuid)
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT $6
+`
+
+type GetListChirpsParams struct {
+	ListID           uuid.UUID
+	ViewerID         uuid.UUID
+	IncludeSensitive bool
+	Before           time.Time
+	BeforeID         uuid.UUID
+	MaxResults       int32
+}
+
+func (q *Queries) GetListChirps(ctx context.Context, arg GetListChirpsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getListChirps,
+		arg.ListID,
+		arg.ViewerID,
+		arg.IncludeSensitive,
+		arg.Before,
+		arg.BeforeID,
+		arg.MaxResults,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.Published,
+			&i.PublishAt,
+			&i.DeletedAt,
+			&i.PinnedPosition,
+			&i.Visibility,
+			&i.ContentWarning,
+			&i.Sensitive,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getScheduledChirps = `-- name: GetScheduledChirps :many
+SELECT id, created_at, updated_at, body, user_id, published, publish_at, deleted_at, pinned_position, visibility, content_warning, sensitive FROM chirps
+WHERE user_id = $1 AND NOT published AND deleted_at IS NULL
+ORDER BY publish_at
 `
 
-func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getChirps)
+func (q *Queries) GetScheduledChirps(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getScheduledChirps, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -107,6 +426,13 @@ func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.Published,
+			&i.PublishAt,
+			&i.DeletedAt,
+			&i.PinnedPosition,
+			&i.Visibility,
+			&i.ContentWarning,
+			&i.Sensitive,
 		); err != nil {
 			return nil, err
 		}
@@ -120,3 +446,362 @@ func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
 	}
 	return items, nil
 }
+
+const getTrashedChirps = `-- name: GetTrashedChirps :many
+SELECT id, created_at, updated_at, body, user_id, published, publish_at, deleted_at, pinned_position, visibility, content_warning, sensitive FROM chirps
+WHERE user_id = $1 AND deleted_at > $2
+ORDER BY deleted_at DESC
+`
+
+type GetTrashedChirpsParams struct {
+	UserID    uuid.UUID
+	DeletedAt sql.NullTime
+}
+
+func (q *Queries) GetTrashedChirps(ctx context.Context, arg GetTrashedChirpsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getTrashedChirps, arg.UserID, arg.DeletedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.Published,
+			&i.PublishAt,
+			&i.DeletedAt,
+			&i.PinnedPosition,
+			&i.Visibility,
+			&i.ContentWarning,
+			&i.Sensitive,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUserChirps = `-- name: GetUserChirps :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.published, chirps.publish_at, chirps.deleted_at, chirps.pinned_position, chirps.visibility, chirps.content_warning, chirps.sensitive FROM chirps
+JOIN users ON users.id = chirps.user_id
+WHERE chirps.user_id = $1 AND users.deletion_requested_at IS NULL
+AND chirps.published AND chirps.deleted_at IS NULL
+AND (chirps.visibility = 'public' OR chirps.user_id = $2)
+AND ($3::boolean OR NOT (chirps.sensitive OR chirps.content_warning <> '') OR chirps.user_id = $2)
+AND NOT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocks.blocker_id = $2 AND blocks.blocked_id = chirps.user_id)
+    OR (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = $2)
+)
+ORDER BY chirps.pinned_position ASC NULLS LAST, chirps.created_at DESC
+`
+
+type GetUserChirpsParams struct {
+	UserID           uuid.UUID
+	ViewerID         uuid.UUID
+	IncludeSensitive bool
+}
+
+func (q *Queries) GetUserChirps(ctx context.Context, arg GetUserChirpsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getUserChirps, arg.UserID, arg.ViewerID, arg.IncludeSensitive)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.Published,
+			&i.PublishAt,
+			&i.DeletedAt,
+			&i.PinnedPosition,
+			&i.Visibility,
+			&i.ContentWarning,
+			&i.Sensitive,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const pinChirp = `-- name: PinChirp :one
+UPDATE chirps
+SET pinned_position = (
+    SELECT COALESCE(MAX(pinned.pinned_position), 0) + 1 FROM chirps AS pinned
+    WHERE pinned.user_id = $2
+)
+WHERE chirps.id = $1 AND chirps.user_id = $2 AND chirps.published AND chirps.deleted_at IS NULL
+AND chirps.pinned_position IS NULL
+RETURNING id, created_at, updated_at, body, user_id, published, publish_at, deleted_at, pinned_position, visibility, content_warning, sensitive
+`
+
+type PinChirpParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) PinChirp(ctx context.Context, arg PinChirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, pinChirp, arg.ID, arg.UserID)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.Published,
+		&i.PublishAt,
+		&i.DeletedAt,
+		&i.PinnedPosition,
+		&i.Visibility,
+		&i.ContentWarning,
+		&i.Sensitive,
+	)
+	return i, err
+}
+
+const publishChirp = `-- name: PublishChirp :one
+UPDATE chirps
+SET published = TRUE, updated_at = $2
+WHERE id = $1 AND NOT published AND deleted_at IS NULL
+RETURNING id, created_at, updated_at, body, user_id, published, publish_at, deleted_at, pinned_position, visibility, content_warning, sensitive
+`
+
+type PublishChirpParams struct {
+	ID        uuid.UUID
+	UpdatedAt time.Time
+}
+
+func (q *Queries) PublishChirp(ctx context.Context, arg PublishChirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, publishChirp, arg.ID, arg.UpdatedAt)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.Published,
+		&i.PublishAt,
+		&i.DeletedAt,
+		&i.PinnedPosition,
+		&i.Visibility,
+		&i.ContentWarning,
+		&i.Sensitive,
+	)
+	return i, err
+}
+
+const purgeTrashedChirps = `-- name: PurgeTrashedChirps :exec
+DELETE FROM chirps
+WHERE deleted_at < $1
+`
+
+func (q *Queries) PurgeTrashedChirps(ctx context.Context, deletedAt sql.NullTime) error {
+	_, err := q.db.ExecContext(ctx, purgeTrashedChirps, deletedAt)
+	return err
+}
+
+const restoreChirp = `-- name: RestoreChirp :one
+UPDATE chirps
+SET deleted_at = NULL, updated_at = $3
+WHERE id = $1 AND user_id = $2 AND deleted_at > $4
+RETURNING id, created_at, updated_at, body, user_id, published, publish_at, deleted_at, pinned_position, visibility, content_warning, sensitive
+`
+
+type RestoreChirpParams struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	UpdatedAt time.Time
+	DeletedAt sql.NullTime
+}
+
+func (q *Queries) RestoreChirp(ctx context.Context, arg RestoreChirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, restoreChirp,
+		arg.ID,
+		arg.UserID,
+		arg.UpdatedAt,
+		arg.DeletedAt,
+	)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.Published,
+		&i.PublishAt,
+		&i.DeletedAt,
+		&i.PinnedPosition,
+		&i.Visibility,
+		&i.ContentWarning,
+		&i.Sensitive,
+	)
+	return i, err
+}
+
+const setChirpPinPosition = `-- name: SetChirpPinPosition :one
+UPDATE chirps
+SET pinned_position = $3
+WHERE id = $1 AND user_id = $2 AND published AND deleted_at IS NULL
+RETURNING id, created_at, updated_at, body, user_id, published, publish_at, deleted_at, pinned_position, visibility, content_warning, sensitive
+`
+
+type SetChirpPinPositionParams struct {
+	ID             uuid.UUID
+	UserID         uuid.UUID
+	PinnedPosition sql.NullInt32
+}
+
+func (q *Queries) SetChirpPinPosition(ctx context.Context, arg SetChirpPinPositionParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, setChirpPinPosition, arg.ID, arg.UserID, arg.PinnedPosition)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.Published,
+		&i.PublishAt,
+		&i.DeletedAt,
+		&i.PinnedPosition,
+		&i.Visibility,
+		&i.ContentWarning,
+		&i.Sensitive,
+	)
+	return i, err
+}
+
+const softDeleteChirp = `-- name: SoftDeleteChirp :one
+UPDATE chirps
+SET deleted_at = $2, pinned_position = NULL
+WHERE id = $1 AND deleted_at IS NULL
+RETURNING id, created_at, updated_at, body, user_id, published, publish_at, deleted_at, pinned_position, visibility, content_warning, sensitive
+`
+
+type SoftDeleteChirpParams struct {
+	ID        uuid.UUID
+	DeletedAt sql.NullTime
+}
+
+func (q *Queries) SoftDeleteChirp(ctx context.Context, arg SoftDeleteChirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, softDeleteChirp, arg.ID, arg.DeletedAt)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.Published,
+		&i.PublishAt,
+		&i.DeletedAt,
+		&i.PinnedPosition,
+		&i.Visibility,
+		&i.ContentWarning,
+		&i.Sensitive,
+	)
+	return i, err
+}
+
+const unpinChirp = `-- name: UnpinChirp :one
+UPDATE chirps
+SET pinned_position = NULL
+WHERE id = $1 AND user_id = $2 AND pinned_position IS NOT NULL
+RETURNING id, created_at, updated_at, body, user_id, published, publish_at, deleted_at, pinned_position, visibility, content_warning, sensitive
+`
+
+type UnpinChirpParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) UnpinChirp(ctx context.Context, arg UnpinChirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, unpinChirp, arg.ID, arg.UserID)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.Published,
+		&i.PublishAt,
+		&i.DeletedAt,
+		&i.PinnedPosition,
+		&i.Visibility,
+		&i.ContentWarning,
+		&i.Sensitive,
+	)
+	return i, err
+}
+
+const updateChirpContent = `-- name: UpdateChirpContent :one
+UPDATE chirps
+SET body = $1, content_warning = $2, sensitive = $3, updated_at = $4
+WHERE id = $5
+RETURNING id, created_at, updated_at, body, user_id, published, publish_at, deleted_at, pinned_position, visibility, content_warning, sensitive
+`
+
+type UpdateChirpContentParams struct {
+	Body           string
+	ContentWarning string
+	Sensitive      bool
+	UpdatedAt      time.Time
+	ID             uuid.UUID
+}
+
+func (q *Queries) UpdateChirpContent(ctx context.Context, arg UpdateChirpContentParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, updateChirpContent,
+		arg.Body,
+		arg.ContentWarning,
+		arg.Sensitive,
+		arg.UpdatedAt,
+		arg.ID,
+	)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.Published,
+		&i.PublishAt,
+		&i.DeletedAt,
+		&i.PinnedPosition,
+		&i.Visibility,
+		&i.ContentWarning,
+		&i.Sensitive,
+	)
+	return i, err
+}
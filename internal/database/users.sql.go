@@ -13,6 +13,34 @@ import (
 	"github.com/google/uuid"
 )
 
+const cancelUserDeletion = `-- name: CancelUserDeletion :exec
+UPDATE users
+SET deletion_requested_at = NULL, updated_at = $1
+WHERE id = $2 AND deletion_requested_at IS NOT NULL
+`
+
+type CancelUserDeletionParams struct {
+	UpdatedAt time.Time
+	ID        uuid.UUID
+}
+
+func (q *Queries) CancelUserDeletion(ctx context.Context, arg CancelUserDeletionParams) error {
+	_, err := q.db.ExecContext(ctx, cancelUserDeletion, arg.UpdatedAt, arg.ID)
+	return err
+}
+
+const countUsersWithRole = `-- name: CountUsersWithRole :one
+SELECT COUNT(*) FROM users
+WHERE role = $1
+`
+
+func (q *Queries) CountUsersWithRole(ctx context.Context, role string) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countUsersWithRole, role)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createUser = `-- name: CreateUser :one
 INSERT INTO users (id, created_at, updated_at, email, password)
 VALUES (
@@ -21,7 +49,7 @@ VALUES (
     $3,
     $4,
     $5
-) RETURNING id, created_at, updated_at, email, password, is_chirpy_red
+) RETURNING id, created_at, updated_at, email, password, is_chirpy_red, role, deletion_requested_at, hide_sensitive
 `
 
 type CreateUserParams struct {
@@ -48,13 +76,16 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Email,
 		&i.Password,
 		&i.IsChirpyRed,
+		&i.Role,
+		&i.DeletionRequestedAt,
+		&i.HideSensitive,
 	)
 	return i, err
 }
 
 const deleteAllUsers = `-- name: DeleteAllUsers :one
 DELETE FROM users
-RETURNING id, created_at, updated_at, email, password, is_chirpy_red
+RETURNING id, created_at, updated_at, email, password, is_chirpy_red, role, deletion_requested_at, hide_sensitive
 `
 
 func (q *Queries) DeleteAllUsers(ctx context.Context) (User, error) {
@@ -67,12 +98,15 @@ func (q *Queries) DeleteAllUsers(ctx context.Context) (User, error) {
 		&i.Email,
 		&i.Password,
 		&i.IsChirpyRed,
+		&i.Role,
+		&i.DeletionRequestedAt,
+		&i.HideSensitive,
 	)
 	return i, err
 }
 
 const getUser = `-- name: GetUser :one
-SELECT id, created_at, updated_at, email, password, is_chirpy_red FROM users
+SELECT id, created_at, updated_at, email, password, is_chirpy_red, role, deletion_requested_at, hide_sensitive FROM users
 WHERE email = $1
 `
 
@@ -86,12 +120,15 @@ func (q *Queries) GetUser(ctx context.Context, email string) (User, error) {
 		&i.Email,
 		&i.Password,
 		&i.IsChirpyRed,
+		&i.Role,
+		&i.DeletionRequestedAt,
+		&i.HideSensitive,
 	)
 	return i, err
 }
 
 const getUserByID = `-- name: GetUserByID :one
-SELECT id, created_at, updated_at, email, password, is_chirpy_red FROM users
+SELECT id, created_at, updated_at, email, password, is_chirpy_red, role, deletion_requested_at, hide_sensitive FROM users
 WHERE id = $1
 `
 
@@ -105,6 +142,144 @@ func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
 		&i.Email,
 		&i.Password,
 		&i.IsChirpyRed,
+		&i.Role,
+		&i.DeletionRequestedAt,
+		&i.HideSensitive,
+	)
+	return i, err
+}
+
+const purgeUsersPendingDeletion = `-- name: PurgeUsersPendingDeletion :many
+DELETE FROM users
+WHERE deletion_requested_at IS NOT NULL AND deletion_requested_at < $1
+RETURNING id
+`
+
+func (q *Queries) PurgeUsersPendingDeletion(ctx context.Context, deletionRequestedAt sql.NullTime) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, purgeUsersPendingDeletion, deletionRequestedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const requestUserDeletion = `-- name: RequestUserDeletion :one
+UPDATE users
+SET deletion_requested_at = $1, updated_at = $2
+WHERE id = $3
+RETURNING id, created_at, updated_at, email, password, is_chirpy_red, role, deletion_requested_at, hide_sensitive
+`
+
+type RequestUserDeletionParams struct {
+	DeletionRequestedAt sql.NullTime
+	UpdatedAt           time.Time
+	ID                  uuid.UUID
+}
+
+func (q *Queries) RequestUserDeletion(ctx context.Context, arg RequestUserDeletionParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, requestUserDeletion, arg.DeletionRequestedAt, arg.UpdatedAt, arg.ID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.Password,
+		&i.IsChirpyRed,
+		&i.Role,
+		&i.DeletionRequestedAt,
+		&i.HideSensitive,
+	)
+	return i, err
+}
+
+const setChirpyRed = `-- name: SetChirpyRed :exec
+UPDATE users
+SET is_chirpy_red = $1
+WHERE id = $2
+`
+
+type SetChirpyRedParams struct {
+	IsChirpyRed sql.NullBool
+	ID          uuid.UUID
+}
+
+func (q *Queries) SetChirpyRed(ctx context.Context, arg SetChirpyRedParams) error {
+	_, err := q.db.ExecContext(ctx, setChirpyRed, arg.IsChirpyRed, arg.ID)
+	return err
+}
+
+const setUserHideSensitive = `-- name: SetUserHideSensitive :one
+UPDATE users
+SET hide_sensitive = $1, updated_at = $2
+WHERE id = $3
+RETURNING id, created_at, updated_at, email, password, is_chirpy_red, role, deletion_requested_at, hide_sensitive
+`
+
+type SetUserHideSensitiveParams struct {
+	HideSensitive bool
+	UpdatedAt     time.Time
+	ID            uuid.UUID
+}
+
+func (q *Queries) SetUserHideSensitive(ctx context.Context, arg SetUserHideSensitiveParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setUserHideSensitive, arg.HideSensitive, arg.UpdatedAt, arg.ID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.Password,
+		&i.IsChirpyRed,
+		&i.Role,
+		&i.DeletionRequestedAt,
+		&i.HideSensitive,
+	)
+	return i, err
+}
+
+const setUserRole = `-- name: SetUserRole :one
+UPDATE users
+SET role = $1, updated_at = $2
+WHERE id = $3
+RETURNING id, created_at, updated_at, email, password, is_chirpy_red, role, deletion_requested_at, hide_sensitive
+`
+
+type SetUserRoleParams struct {
+	Role      string
+	UpdatedAt time.Time
+	ID        uuid.UUID
+}
+
+func (q *Queries) SetUserRole(ctx context.Context, arg SetUserRoleParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setUserRole, arg.Role, arg.UpdatedAt, arg.ID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.Password,
+		&i.IsChirpyRed,
+		&i.Role,
+		&i.DeletionRequestedAt,
+		&i.HideSensitive,
 	)
 	return i, err
 }
@@ -114,7 +289,7 @@ WITH updated_user AS (
     UPDATE users
     SET password = $1, updated_at = $2, email = $3
     WHERE id = $4
-    RETURNING id, created_at, updated_at, email, password, is_chirpy_red
+    RETURNING id, created_at, updated_at, email, password, is_chirpy_red, role, deletion_requested_at, hide_sensitive
 )
 SELECT updated_user.id, updated_user.email, refresh_tokens.tokens, updated_user.updated_at, updated_user.created_at, updated_user.is_chirpy_red
 FROM updated_user
@@ -156,23 +331,18 @@ func (q *Queries) UpdatePassword(ctx context.Context, arg UpdatePasswordParams)
 	return i, err
 }
 
-const upgradeUsertoRed = `-- name: UpgradeUsertoRed :one
+const updatePasswordHash = `-- name: UpdatePasswordHash :exec
 UPDATE users
-SET is_chirpy_red = TRUE
-WHERE id = $1
-RETURNING id, created_at, updated_at, email, password, is_chirpy_red
+SET password = $1
+WHERE id = $2
 `
 
-func (q *Queries) UpgradeUsertoRed(ctx context.Context, id uuid.UUID) (User, error) {
-	row := q.db.QueryRowContext(ctx, upgradeUsertoRed, id)
-	var i User
-	err := row.Scan(
-		&i.ID,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-		&i.Email,
-		&i.Password,
-		&i.IsChirpyRed,
-	)
-	return i, err
+type UpdatePasswordHashParams struct {
+	Password string
+	ID       uuid.UUID
+}
+
+func (q *Queries) UpdatePasswordHash(ctx context.Context, arg UpdatePasswordHashParams) error {
+	_, err := q.db.ExecContext(ctx, updatePasswordHash, arg.Password, arg.ID)
+	return err
 }
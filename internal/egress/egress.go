@@ -0,0 +1,77 @@
+package egress
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// ErrBlockedAddress is returned for addresses outbound requests must not
+// reach: loopback, private, link-local, unspecified and the like.
+var ErrBlockedAddress = errors.New("address is not publicly routable")
+
+// carrierGradeNAT is 100.64.0.0/10, shared address space that is private in
+// all but name.
+var carrierGradeNAT = netip.MustParsePrefix("100.64.0.0/10")
+
+// Allowed reports whether addr is a public unicast address.
+func Allowed(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsValid() &&
+		addr.IsGlobalUnicast() &&
+		!addr.IsPrivate() &&
+		!carrierGradeNAT.Contains(addr)
+}
+
+// CheckHost resolves host and fails with ErrBlockedAddress if any of its
+// addresses is not Allowed.
+func CheckHost(ctx context.Context, host string) error {
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return err
+	}
+	for _, addr := range addrs {
+		if !Allowed(addr) {
+			return ErrBlockedAddress
+		}
+	}
+	return nil
+}
+
+// control vets the address actually being dialled, after DNS resolution, so
+// a host that resolved to a public address when it was checked can't be
+// rebound to a private one later.
+func control(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !Allowed(addrPort.Addr()) {
+		return ErrBlockedAddress
+	}
+	return nil
+}
+
+// NewClient returns a client that only connects to Allowed addresses. It
+// ignores proxy settings, which would hide the real destination from the
+// check, and does not follow redirects.
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout, Control: control}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			Proxy:               nil,
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConns:        100,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
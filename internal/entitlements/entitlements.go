@@ -0,0 +1,86 @@
+package entitlements
+
+import "fmt"
+
+// Entitlement names a paid feature, as reported to clients in 402 responses.
+type Entitlement string
+
+const (
+	LongChirps       Entitlement = "long_chirps"
+	ChirpEditing     Entitlement = "chirp_editing"
+	ScheduledChirps  Entitlement = "scheduled_chirps"
+	HigherRateLimits Entitlement = "higher_rate_limits"
+	ChirpAnalytics   Entitlement = "chirp_analytics"
+)
+
+const (
+	PlanFree = "free"
+	PlanRed  = "red"
+)
+
+// Plan is what a user may do. Handlers consult it rather than checking
+// is_chirpy_red themselves.
+type Plan struct {
+	Name           string
+	MaxChirpLength int
+	ChirpsPerHour  int
+	entitlements   map[Entitlement]bool
+}
+
+var plans = map[string]Plan{
+	PlanFree: {
+		Name:           PlanFree,
+		MaxChirpLength: 140,
+		ChirpsPerHour:  30,
+		entitlements:   map[Entitlement]bool{},
+	},
+	PlanRed: {
+		Name:           PlanRed,
+		MaxChirpLength: 1000,
+		ChirpsPerHour:  300,
+		entitlements: map[Entitlement]bool{
+			LongChirps:       true,
+			ChirpEditing:     true,
+			ScheduledChirps:  true,
+			HigherRateLimits: true,
+			ChirpAnalytics:   true,
+		},
+	},
+}
+
+// ForUser returns the plan for a user with the given Chirpy Red status.
+func ForUser(isRed bool) Plan {
+	if isRed {
+		return plans[PlanRed]
+	}
+	return plans[PlanFree]
+}
+
+// Has reports whether the plan includes entitlement.
+func (plan Plan) Has(entitlement Entitlement) bool {
+	return plan.entitlements[entitlement]
+}
+
+// Require returns a *MissingError when the plan lacks entitlement.
+func (plan Plan) Require(entitlement Entitlement) error {
+	if plan.Has(entitlement) {
+		return nil
+	}
+	return &MissingError{Entitlement: entitlement, Plan: plan.Name, UpgradeAvailable: plans[PlanRed].Has(entitlement)}
+}
+
+// MissingError explains which entitlement a request needed.
+type MissingError struct {
+	Entitlement Entitlement
+	Plan        string
+	// UpgradeAvailable is set when Chirpy Red would grant the entitlement,
+	// answered with 402 rather than 403.
+	UpgradeAvailable bool
+}
+
+func (err *MissingError) Error() string {
+	if err.UpgradeAvailable {
+		return fmt.Sprintf("The %s plan does not include %s, upgrade to Chirpy Red.", err.Plan, err.Entitlement)
+	}
+	return fmt.Sprintf("The %s plan does not include %s.", err.Plan, err.Entitlement)
+}
@@ -0,0 +1,41 @@
+package entitlements
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPlans(t *testing.T) {
+	free := ForUser(false)
+	red := ForUser(true)
+
+	for _, entitlement := range []Entitlement{LongChirps, ChirpEditing, ScheduledChirps, HigherRateLimits, ChirpAnalytics} {
+		if free.Has(entitlement) {
+			t.Errorf("free plan should not include %s", entitlement)
+		}
+		if !red.Has(entitlement) {
+			t.Errorf("red plan should include %s", entitlement)
+		}
+		if err := red.Require(entitlement); err != nil {
+			t.Errorf("red plan require %s: %v", entitlement, err)
+		}
+	}
+
+	if free.MaxChirpLength != 140 || red.MaxChirpLength <= free.MaxChirpLength {
+		t.Errorf("unexpected chirp lengths free=%d red=%d", free.MaxChirpLength, red.MaxChirpLength)
+	}
+	if red.ChirpsPerHour <= free.ChirpsPerHour {
+		t.Errorf("red should have a higher rate limit than free")
+	}
+}
+
+func TestRequire(t *testing.T) {
+	err := ForUser(false).Require(ChirpEditing)
+	missing := &MissingError{}
+	if !errors.As(err, &missing) {
+		t.Fatalf("expected a MissingError, got %v", err)
+	}
+	if missing.Entitlement != ChirpEditing || missing.Plan != PlanFree || !missing.UpgradeAvailable {
+		t.Errorf("unexpected error %+v", missing)
+	}
+}
@@ -0,0 +1,169 @@
+// Package jobs runs background work queued in the Postgres jobs table.
+// Replicas claim due jobs with FOR UPDATE SKIP LOCKED, so each job runs on
+// one replica at a time, and failed jobs are retried with backoff.
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/anantashahane/Chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// Statuses stored in jobs.status.
+const (
+	StatusPending   = "pending"
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusDead      = "dead"
+)
+
+const defaultMaxAttempts = 10
+
+// Handler runs one job given its raw JSON payload.
+type Handler func(ctx context.Context, payload []byte) error
+
+// Options control when and how often a job runs. The zero value runs it
+// straight away with the default number of attempts.
+type Options struct {
+	RunAt       time.Time
+	MaxAttempts int32
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not worth retrying, the job is marked dead at once.
+func Permanent(err error) error {
+	return permanentError{err: err}
+}
+
+// Runner dispatches claimed jobs to the handler registered for their kind.
+type Runner struct {
+	db       *database.Queries
+	handlers map[string]Handler
+
+	// Lease is how long a claimed job is hidden from other replicas. A job
+	// whose runner dies is picked up again once its lease runs out.
+	Lease time.Duration
+	Batch int32
+}
+
+func NewRunner(db *database.Queries) *Runner {
+	return &Runner{
+		db:       db,
+		handlers: map[string]Handler{},
+		Lease:    5 * time.Minute,
+		Batch:    20,
+	}
+}
+
+// Register adds the handler for kind, decoding each payload into T first.
+// Payloads that do not decode are not retried.
+func Register[T any](runner *Runner, kind string, handle func(context.Context, T) error) {
+	runner.handlers[kind] = func(ctx context.Context, payload []byte) error {
+		var data T
+		if err := json.Unmarshal(payload, &data); err != nil {
+			return Permanent(fmt.Errorf("decode %s payload: %w", kind, err))
+		}
+		return handle(ctx, data)
+	}
+}
+
+// Enqueue queues a job through q. Given Queries bound to a transaction the
+// job is only queued if that transaction commits, which makes the jobs table
+// an outbox for side effects of the change being written.
+func Enqueue(ctx context.Context, q *database.Queries, kind string, payload any, options Options) (database.Job, error) {
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return database.Job{}, err
+	}
+	now := time.Now()
+	if options.RunAt.IsZero() {
+		options.RunAt = now
+	}
+	if options.MaxAttempts <= 0 {
+		options.MaxAttempts = defaultMaxAttempts
+	}
+	return q.EnqueueJob(ctx, database.EnqueueJobParams{
+		ID:          uuid.New(),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		Kind:        kind,
+		Payload:     string(encoded),
+		MaxAttempts: options.MaxAttempts,
+		RunAt:       options.RunAt,
+	})
+}
+
+// Backoff is the wait before retrying a job that has failed attempts times:
+// 10s doubling per attempt, capped at an hour.
+func Backoff(attempts int32) time.Duration {
+	backoff := 10 * time.Second
+	for i := int32(1); i < attempts && backoff < time.Hour; i++ {
+		backoff *= 2
+	}
+	return min(backoff, time.Hour)
+}
+
+// RunOnce claims a batch of due jobs, runs them and records the outcome. It
+// returns how many jobs it ran.
+func (runner *Runner) RunOnce(ctx context.Context) (int, error) {
+	now := time.Now()
+	claimed, err := runner.db.ClaimDueJobs(ctx, database.ClaimDueJobsParams{
+		LeaseUntil: sql.NullTime{Time: now.Add(runner.Lease), Valid: true},
+		Now:        now,
+		MaxResults: runner.Batch,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, job := range claimed {
+		jobErr := runner.run(ctx, job)
+
+		finish := database.FinishJobParams{
+			Status:    StatusSucceeded,
+			RunAt:     job.RunAt,
+			UpdatedAt: time.Now(),
+			ID:        job.ID,
+		}
+		if jobErr != nil {
+			finish.LastError = sql.NullString{String: jobErr.Error(), Valid: true}
+			finish.Status = StatusPending
+			finish.RunAt = time.Now().Add(Backoff(job.Attempts))
+			var permanent permanentError
+			if errors.As(jobErr, &permanent) || job.Attempts >= job.MaxAttempts {
+				finish.Status = StatusDead
+			}
+		}
+		if err := runner.db.FinishJob(ctx, finish); err != nil {
+			return len(claimed), fmt.Errorf("record job %s: %w", job.ID, err)
+		}
+	}
+	return len(claimed), nil
+}
+
+// run calls the job's handler, turning a panic into an error so one bad job
+// cannot take the runner down.
+func (runner *Runner) run(ctx context.Context, job database.Job) (err error) {
+	handle, ok := runner.handlers[job.Kind]
+	if !ok {
+		return Permanent(fmt.Errorf("no handler for job kind %q", job.Kind))
+	}
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("job panicked: %v", recovered)
+		}
+	}()
+	return handle(ctx, []byte(job.Payload))
+}
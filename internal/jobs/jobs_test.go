@@ -0,0 +1,66 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/anantashahane/Chirpy/internal/database"
+)
+
+type greeting struct {
+	Name string `json:"name"`
+}
+
+func TestRegisterDecodesPayload(t *testing.T) {
+	runner := NewRunner(nil)
+	got := ""
+	Register(runner, "greet", func(ctx context.Context, data greeting) error {
+		got = data.Name
+		return nil
+	})
+
+	if err := runner.run(context.Background(), database.Job{Kind: "greet", Payload: `{"name":"Chirpy"}`}); err != nil {
+		t.Fatal(err)
+	}
+	if got != "Chirpy" {
+		t.Errorf("handler got %q, want Chirpy", got)
+	}
+
+	err := runner.run(context.Background(), database.Job{Kind: "greet", Payload: `not json`})
+	if !errors.As(err, &permanentError{}) {
+		t.Errorf("undecodable payload should fail permanently, got %v", err)
+	}
+}
+
+func TestRunFailures(t *testing.T) {
+	runner := NewRunner(nil)
+	Register(runner, "panics", func(ctx context.Context, data greeting) error {
+		panic("boom")
+	})
+
+	err := runner.run(context.Background(), database.Job{Kind: "panics", Payload: `{}`})
+	if err == nil || errors.As(err, &permanentError{}) {
+		t.Errorf("a panic should be a retryable error, got %v", err)
+	}
+
+	err = runner.run(context.Background(), database.Job{Kind: "unknown", Payload: `{}`})
+	if !errors.As(err, &permanentError{}) {
+		t.Errorf("unknown kinds should fail permanently, got %v", err)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	cases := map[int32]time.Duration{
+		1:  10 * time.Second,
+		2:  20 * time.Second,
+		4:  80 * time.Second,
+		30: time.Hour,
+	}
+	for attempts, want := range cases {
+		if got := Backoff(attempts); got != want {
+			t.Errorf("Backoff(%d) = %s, want %s", attempts, got, want)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// mockProvider is a minimal OIDC provider that hands out an ID token for
+// the single code "good-code".
+type mockProvider struct {
+	server   *httptest.Server
+	key      *rsa.PrivateKey
+	clientID string
+	nonce    string
+	subject  string
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock := &mockProvider{key: key, clientID: "chirpy", subject: "external-user-1"}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 mock.server.URL,
+			"authorization_endpoint": mock.server.URL + "/authorize",
+			"token_endpoint":         mock.server.URL + "/token",
+			"jwks_uri":               mock.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "test-key",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.PublicKey.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.PublicKey.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		clientID, _, _ := r.BasicAuth()
+		if r.Form.Get("code") != "good-code" || clientID != mock.clientID {
+			w.WriteHeader(400)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id_token": mock.idToken(t, mock.clientID, time.Hour)})
+	})
+	mock.server = httptest.NewServer(mux)
+	t.Cleanup(mock.server.Close)
+	return mock
+}
+
+func (mock *mockProvider) idToken(t *testing.T, audience string, expiresIn time.Duration) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    mock.server.URL,
+			Subject:   mock.subject,
+			Audience:  jwt.ClaimStrings{audience},
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
+		},
+		Email:         "user@example.com",
+		EmailVerified: true,
+		Nonce:         mock.nonce,
+	})
+	token.Header["kid"] = "test-key"
+	signed, err := token.SignedString(mock.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	mock := newMockProvider(t)
+	mock.nonce = "expected-nonce"
+	ctx := context.Background()
+
+	provider, err := Discover(ctx, mock.server.URL, mock.clientID, "secret", "http://localhost:8080/api/oidc/callback")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authURL, err := url.Parse(provider.AuthCodeURL("some-state", "expected-nonce"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if authURL.Query().Get("state") != "some-state" || authURL.Query().Get("nonce") != "expected-nonce" {
+		t.Errorf("auth code url missing state or nonce: %s", authURL)
+	}
+
+	rawIDToken, err := provider.Exchange(ctx, "good-code")
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := provider.VerifyIDToken(ctx, rawIDToken, "expected-nonce")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != mock.subject || claims.Email != "user@example.com" || !claims.EmailVerified {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+
+	if _, err := provider.Exchange(ctx, "bad-code"); err == nil {
+		t.Errorf("exchange with a bad code should fail")
+	}
+}
+
+func TestVerifyIDTokenRejects(t *testing.T) {
+	mock := newMockProvider(t)
+	ctx := context.Background()
+	provider, err := Discover(ctx, mock.server.URL, mock.clientID, "secret", "http://localhost:8080/api/oidc/callback")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged := jwt.NewWithClaims(jwt.SigningMethodRS256, Claims{RegisteredClaims: jwt.RegisteredClaims{
+		Issuer:    mock.server.URL,
+		Subject:   mock.subject,
+		Audience:  jwt.ClaimStrings{mock.clientID},
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}})
+	forged.Header["kid"] = "test-key"
+	forgedToken, err := forged.SignedString(otherKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mock.nonce = "n"
+	tests := map[string]struct {
+		token string
+		nonce string
+	}{
+		"wrong audience": {token: mock.idToken(t, "someone-else", time.Hour), nonce: "n"},
+		"expired":        {token: mock.idToken(t, mock.clientID, -time.Hour), nonce: "n"},
+		"nonce mismatch": {token: mock.idToken(t, mock.clientID, time.Hour), nonce: "other"},
+		"forged":         {token: forgedToken, nonce: ""},
+	}
+	for name, test := range tests {
+		if _, err := provider.VerifyIDToken(ctx, test.token, test.nonce); err == nil {
+			t.Errorf("%s: token should have been rejected", name)
+		}
+	}
+}
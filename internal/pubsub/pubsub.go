@@ -0,0 +1,112 @@
+package pubsub
+
+import (
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// Event is one message on the hub. IDs grow with each event, so a client
+// that reconnects can say which one it saw last.
+type Event struct {
+	ID      int64
+	Type    string
+	Topics  []string
+	ActorID uuid.UUID
+	Data    []byte
+}
+
+// Hub fans events out to the subscribers of their topics. It is in process;
+// replicas each run their own and are fed the same events from outside.
+type Hub struct {
+	mu          sync.Mutex
+	subscribers map[string]map[*Subscription]struct{}
+}
+
+// Subscription receives the events of one topic through a bounded buffer.
+type Subscription struct {
+	hub     *Hub
+	topic   string
+	events  chan Event
+	evicted bool
+}
+
+func NewHub() *Hub {
+	return &Hub{subscribers: map[string]map[*Subscription]struct{}{}}
+}
+
+// Subscribe starts delivering topic's events into a buffer of the given size.
+// Call Close when done.
+func (hub *Hub) Subscribe(topic string, buffer int) *Subscription {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	sub := &Subscription{hub: hub, topic: topic, events: make(chan Event, buffer)}
+	if hub.subscribers[topic] == nil {
+		hub.subscribers[topic] = map[*Subscription]struct{}{}
+	}
+	hub.subscribers[topic][sub] = struct{}{}
+	return sub
+}
+
+// Publish hands event to every subscriber of its topics without waiting. A
+// subscriber whose buffer is full is evicted rather than slowing down the
+// rest.
+func (hub *Hub) Publish(event Event) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	for _, topic := range event.Topics {
+		for sub := range hub.subscribers[topic] {
+			select {
+			case sub.events <- event:
+			default:
+				sub.evicted = true
+				hub.remove(sub)
+			}
+		}
+	}
+}
+
+// Subscribers counts the subscriptions of topic.
+func (hub *Hub) Subscribers(topic string) int {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	return len(hub.subscribers[topic])
+}
+
+// remove drops sub and closes its channel. The caller holds hub.mu.
+func (hub *Hub) remove(sub *Subscription) {
+	subs, ok := hub.subscribers[sub.topic]
+	if !ok {
+		return
+	}
+	if _, ok := subs[sub]; !ok {
+		return
+	}
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(hub.subscribers, sub.topic)
+	}
+	close(sub.events)
+}
+
+// Events is closed when the subscription is closed or evicted.
+func (sub *Subscription) Events() <-chan Event {
+	return sub.events
+}
+
+// Evicted reports whether the hub dropped the subscription for falling
+// behind.
+func (sub *Subscription) Evicted() bool {
+	sub.hub.mu.Lock()
+	defer sub.hub.mu.Unlock()
+	return sub.evicted
+}
+
+// Close stops delivery. Closing twice, or after eviction, is harmless.
+func (sub *Subscription) Close() {
+	sub.hub.mu.Lock()
+	defer sub.hub.mu.Unlock()
+	sub.hub.remove(sub)
+}
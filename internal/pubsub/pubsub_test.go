@@ -0,0 +1,75 @@
+package pubsub
+
+import "testing"
+
+func TestPublishByTopic(t *testing.T) {
+	hub := NewHub()
+	public := hub.Subscribe("public", 4)
+	defer public.Close()
+	tagged := hub.Subscribe("hashtag:go", 4)
+	defer tagged.Close()
+
+	hub.Publish(Event{ID: 1, Topics: []string{"public"}})
+	hub.Publish(Event{ID: 2, Topics: []string{"public", "hashtag:go"}})
+
+	if got := (<-public.Events()).ID; got != 1 {
+		t.Errorf("public got event %d first, want 1", got)
+	}
+	if got := (<-public.Events()).ID; got != 2 {
+		t.Errorf("public got event %d second, want 2", got)
+	}
+	if got := (<-tagged.Events()).ID; got != 2 {
+		t.Errorf("hashtag got event %d, want 2", got)
+	}
+	select {
+	case event := <-tagged.Events():
+		t.Errorf("hashtag got unexpected event %d", event.ID)
+	default:
+	}
+}
+
+func TestSlowSubscriberIsEvicted(t *testing.T) {
+	hub := NewHub()
+	slow := hub.Subscribe("public", 1)
+	fast := hub.Subscribe("public", 4)
+	defer fast.Close()
+
+	hub.Publish(Event{ID: 1, Topics: []string{"public"}})
+	hub.Publish(Event{ID: 2, Topics: []string{"public"}})
+
+	if !slow.Evicted() {
+		t.Fatal("a full subscriber should be evicted")
+	}
+	if fast.Evicted() {
+		t.Error("a subscriber with room should stay")
+	}
+	if event, ok := <-slow.Events(); !ok || event.ID != 1 {
+		t.Errorf("buffered event should still be readable, got %d %v", event.ID, ok)
+	}
+	if _, ok := <-slow.Events(); ok {
+		t.Error("an evicted subscription's channel should be closed")
+	}
+	if got := hub.Subscribers("public"); got != 1 {
+		t.Errorf("hub has %d public subscribers, want 1", got)
+	}
+
+	slow.Close()
+}
+
+func TestClose(t *testing.T) {
+	hub := NewHub()
+	sub := hub.Subscribe("public", 1)
+	sub.Close()
+	sub.Close()
+
+	hub.Publish(Event{ID: 1, Topics: []string{"public"}})
+	if _, ok := <-sub.Events(); ok {
+		t.Error("a closed subscription should not receive events")
+	}
+	if sub.Evicted() {
+		t.Error("closing is not eviction")
+	}
+	if got := hub.Subscribers("public"); got != 0 {
+		t.Errorf("hub has %d public subscribers, want 0", got)
+	}
+}
@@ -0,0 +1,52 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter counts events per key in fixed windows. It is in process, so each
+// replica enforces its own limit.
+type Limiter struct {
+	window time.Duration
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	start time.Time
+	count int
+}
+
+func New(window time.Duration) *Limiter {
+	return &Limiter{window: window, buckets: map[string]*bucket{}}
+}
+
+// Allow records an event for key if fewer than limit happened in the current
+// window. When refused it returns how long until the window resets.
+func (limiter *Limiter) Allow(key string, limit int, now time.Time) (bool, time.Duration) {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	current, ok := limiter.buckets[key]
+	if !ok || now.Sub(current.start) >= limiter.window {
+		limiter.sweep(now)
+		current = &bucket{start: now}
+		limiter.buckets[key] = current
+	}
+	if current.count >= limit {
+		return false, current.start.Add(limiter.window).Sub(now)
+	}
+	current.count++
+	return true, 0
+}
+
+// sweep drops finished windows so idle keys don't pile up.
+func (limiter *Limiter) sweep(now time.Time) {
+	for key, old := range limiter.buckets {
+		if now.Sub(old.start) >= limiter.window {
+			delete(limiter.buckets, key)
+		}
+	}
+}
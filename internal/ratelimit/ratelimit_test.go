@@ -0,0 +1,31 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAllow(t *testing.T) {
+	limiter := New(time.Hour)
+	start := time.Now()
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := limiter.Allow("user", 3, start); !ok {
+			t.Fatalf("event %d should be allowed", i)
+		}
+	}
+	ok, retryAfter := limiter.Allow("user", 3, start.Add(10*time.Minute))
+	if ok {
+		t.Fatal("fourth event in the window should be refused")
+	}
+	if retryAfter != 50*time.Minute {
+		t.Errorf("expected retry after 50m, got %s", retryAfter)
+	}
+
+	if ok, _ := limiter.Allow("someone-else", 3, start); !ok {
+		t.Errorf("keys should be limited independently")
+	}
+	if ok, _ := limiter.Allow("user", 3, start.Add(time.Hour)); !ok {
+		t.Errorf("a new window should allow events again")
+	}
+}
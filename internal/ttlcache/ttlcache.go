@@ -0,0 +1,55 @@
+package ttlcache
+
+import (
+	"sync"
+	"time"
+)
+
+// Cache keeps values for a fixed time after they were set. It is in process,
+// so each replica caches on its own.
+type Cache[V any] struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[string]entry[V]
+}
+
+type entry[V any] struct {
+	value V
+	setAt time.Time
+}
+
+func New[V any](ttl time.Duration) *Cache[V] {
+	return &Cache[V]{ttl: ttl, entries: map[string]entry[V]{}}
+}
+
+// Get returns the value stored under key unless it is missing or expired.
+func (cache *Cache[V]) Get(key string, now time.Time) (V, bool) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	current, ok := cache.entries[key]
+	if !ok || now.Sub(current.setAt) >= cache.ttl {
+		var zero V
+		return zero, false
+	}
+	return current.value, true
+}
+
+// Set stores value under key, replacing whatever was there.
+func (cache *Cache[V]) Set(key string, value V, now time.Time) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	cache.sweep(now)
+	cache.entries[key] = entry[V]{value: value, setAt: now}
+}
+
+// sweep drops expired entries so keys that are no longer read don't pile up.
+func (cache *Cache[V]) sweep(now time.Time) {
+	for key, old := range cache.entries {
+		if now.Sub(old.setAt) >= cache.ttl {
+			delete(cache.entries, key)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package ttlcache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetSet(t *testing.T) {
+	cache := New[int](time.Minute)
+	start := time.Now()
+
+	if _, ok := cache.Get("trends", start); ok {
+		t.Fatal("an empty cache should miss")
+	}
+	cache.Set("trends", 1, start)
+	if value, ok := cache.Get("trends", start.Add(59*time.Second)); !ok || value != 1 {
+		t.Fatalf("expected a hit with 1, got %d, %v", value, ok)
+	}
+	if _, ok := cache.Get("trends", start.Add(time.Minute)); ok {
+		t.Errorf("entries should expire after the ttl")
+	}
+
+	cache.Set("trends", 2, start.Add(time.Minute))
+	if value, _ := cache.Get("trends", start.Add(time.Minute)); value != 2 {
+		t.Errorf("Set should replace the old value, got %d", value)
+	}
+	if _, ok := cache.Get("other", start); ok {
+		t.Errorf("keys should be cached independently")
+	}
+}
+
+func TestSetSweepsExpired(t *testing.T) {
+	cache := New[string](time.Minute)
+	start := time.Now()
+
+	cache.Set("old", "a", start)
+	cache.Set("new", "b", start.Add(2*time.Minute))
+	if len(cache.entries) != 1 {
+		t.Errorf("expired entries should be swept, %d left", len(cache.entries))
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/anantashahane/Chirpy/internal/database"
+	"github.com/anantashahane/Chirpy/internal/jobs"
+)
+
+// runEvery calls job straight away and then once per interval until ctx is
+// cancelled. Jobs must be safe to run on several replicas at once.
+func runEvery(ctx context.Context, interval time.Duration, job func(context.Context)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		job(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// purgeDeletedAccounts hard deletes accounts whose deletion grace period has
+// passed. Their export archives are removed from disk first. Chirps and
+// refresh tokens go with them through ON DELETE CASCADE, and so do their
+// conversation memberships; a conversation is only deleted once it has no
+// members left.
+func (cfg *apiConfig) purgeDeletedAccounts(ctx context.Context) {
+	cutoff := time.Now().Add(-cfg.deletionGracePeriod)
+	exports, err := cfg.db.DeleteDataExportsOfPurgeableUsers(ctx, sql.NullTime{Time: cutoff, Valid: true})
+	if err != nil {
+		fmt.Println("Account purge failed: " + err.Error())
+		return
+	}
+	for _, export := range exports {
+		cfg.removeExportFile(export)
+	}
+
+	purged, err := cfg.db.PurgeUsersPendingDeletion(ctx, sql.NullTime{Time: cutoff, Valid: true})
+	if err != nil {
+		fmt.Println("Account purge failed: " + err.Error())
+		return
+	}
+	if len(purged) > 0 {
+		fmt.Printf("Purged %d deleted account(s).\n", len(purged))
+	}
+	if err := cfg.db.DeleteEmptyConversations(ctx); err != nil {
+		fmt.Println("Conversation cleanup failed: " + err.Error())
+	}
+}
+
+// Kinds of job queued in the jobs table.
+const (
+	jobBuildDataExport    = "data_export.build"
+	jobFanOutWebhookEvent = "webhook_event.fan_out"
+	jobPublishChirp       = "chirp.publish"
+)
+
+func (cfg *apiConfig) registerJobs() {
+	jobs.Register(cfg.jobRunner, jobBuildDataExport, cfg.buildDataExportJob)
+	jobs.Register(cfg.jobRunner, jobFanOutWebhookEvent, cfg.fanOutWebhookEvent)
+	jobs.Register(cfg.jobRunner, jobPublishChirp, cfg.publishScheduledChirp)
+}
+
+// inTx runs fn with Queries bound to a transaction, committing if fn
+// succeeds and rolling back otherwise.
+func (cfg *apiConfig) inTx(ctx context.Context, fn func(q *database.Queries) error) error {
+	tx, err := cfg.dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := fn(cfg.db.WithTx(tx)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// runJobs works through due jobs until none are left.
+func (cfg *apiConfig) runJobs(ctx context.Context) {
+	for ctx.Err() == nil {
+		ran, err := cfg.jobRunner.RunOnce(ctx)
+		if err != nil {
+			fmt.Println("Job runner failed: " + err.Error())
+			return
+		}
+		if ran == 0 {
+			return
+		}
+	}
+}
+
+// purgeFinishedJobs forgets jobs that succeeded over a week ago. Dead jobs
+// are kept for inspection.
+func (cfg *apiConfig) purgeFinishedJobs(ctx context.Context) {
+	err := cfg.db.PurgeFinishedJobs(ctx, time.Now().Add(-7*24*time.Hour))
+	if err != nil {
+		fmt.Println("Job purge failed: " + err.Error())
+	}
+}
+
+type jobResponseBody struct {
+	ID          string `json:"id"`
+	CreatedAt   string `json:"created_at"`
+	UpdatedAt   string `json:"updated_at"`
+	Kind        string `json:"kind"`
+	Payload     string `json:"payload"`
+	Status      string `json:"status"`
+	Attempts    int32  `json:"attempts"`
+	MaxAttempts int32  `json:"max_attempts"`
+	RunAt       string `json:"run_at"`
+	LastError   string `json:"last_error,omitempty"`
+}
+
+func (apiCfg *apiConfig) listJobsHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	limit, err := strconv.Atoi(req.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 || limit > 500 {
+		limit = 100
+	}
+
+	queued, err := apiCfg.db.ListJobs(context.Background(), database.ListJobsParams{
+		Status:     req.URL.Query().Get("status"),
+		MaxResults: int32(limit),
+	})
+	if err != nil {
+		responseWriter.WriteHeader(500)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Internal Server failed to access database."))
+		return
+	}
+
+	responseBody := []jobResponseBody{}
+	for _, job := range queued {
+		responseBody = append(responseBody, jobResponseBody{
+			ID:          job.ID.String(),
+			CreatedAt:   job.CreatedAt.String(),
+			UpdatedAt:   job.UpdatedAt.String(),
+			Kind:        job.Kind,
+			Payload:     job.Payload,
+			Status:      job.Status,
+			Attempts:    job.Attempts,
+			MaxAttempts: job.MaxAttempts,
+			RunAt:       job.RunAt.String(),
+			LastError:   job.LastError.String,
+		})
+	}
+	responseWriter.Header().Set("Content-Type", "application/json")
+	responseWriter.WriteHeader(200)
+	json.NewEncoder(responseWriter).Encode(responseBody)
+}
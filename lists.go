@@ -0,0 +1,454 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/anantashahane/Chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	maxListNameLength        = 50
+	maxListDescriptionLength = 160
+	maxListMembers           = 500
+)
+
+type listResponseBody struct {
+	ID          string `json:"id"`
+	OwnerID     string `json:"owner_id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Private     bool   `json:"private"`
+	CreatedAt   string `json:"created_at"`
+	UpdatedAt   string `json:"updated_at"`
+}
+
+func listResponse(list database.List) listResponseBody {
+	return listResponseBody{
+		ID:          list.ID.String(),
+		OwnerID:     list.OwnerID.String(),
+		Name:        list.Name,
+		Description: list.Description,
+		Private:     list.IsPrivate,
+		CreatedAt:   list.CreatedAt.String(),
+		UpdatedAt:   list.UpdatedAt.String(),
+	}
+}
+
+type listRequestBody struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Private     bool   `json:"private"`
+}
+
+// decodeListBody reads a list's name, description and privacy, answering
+// the request itself when they are invalid.
+func decodeListBody(responseWriter http.ResponseWriter, req *http.Request) (listRequestBody, bool) {
+	decoder := json.NewDecoder(req.Body)
+	decoder.DisallowUnknownFields()
+	requestData := listRequestBody{}
+	if err := decoder.Decode(&requestData); err != nil {
+		responseWriter.WriteHeader(400)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Json Decode failed."))
+		return listRequestBody{}, false
+	}
+	if requestData.Name == "" || !validateChirp(requestData.Name, maxListNameLength) {
+		responseWriter.WriteHeader(400)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("List names need between 1 and 50 characters."))
+		return listRequestBody{}, false
+	}
+	if !validateChirp(requestData.Description, maxListDescriptionLength) {
+		responseWriter.WriteHeader(400)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("List descriptions can have at most 160 characters."))
+		return listRequestBody{}, false
+	}
+	return requestData, true
+}
+
+func (apiCfg *apiConfig) createListHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	userData, _ := userFromContext(req.Context())
+
+	requestData, ok := decodeListBody(responseWriter, req)
+	if !ok {
+		return
+	}
+
+	list, err := apiCfg.db.CreateList(context.Background(), database.CreateListParams{
+		ID:          uuid.New(),
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+		OwnerID:     userData.ID,
+		Name:        requestData.Name,
+		Description: requestData.Description,
+		IsPrivate:   requestData.Private,
+	})
+	if err != nil {
+		responseWriter.WriteHeader(500)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Unable to create list."))
+		return
+	}
+
+	responseWriter.Header().Set("Content-Type", "application/json")
+	responseWriter.WriteHeader(201)
+	json.NewEncoder(responseWriter).Encode(listResponse(list))
+}
+
+// writeLists answers with owner's lists, the private ones too when
+// includePrivate is set.
+func (apiCfg *apiConfig) writeLists(responseWriter http.ResponseWriter, ownerID uuid.UUID, includePrivate bool) {
+	lists, err := apiCfg.db.GetListsByOwner(context.Background(), database.GetListsByOwnerParams{
+		OwnerID:        ownerID,
+		IncludePrivate: includePrivate,
+	})
+	if err != nil {
+		responseWriter.WriteHeader(500)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Internal Server failed to access database."))
+		return
+	}
+
+	responseBody := []listResponseBody{}
+	for _, list := range lists {
+		responseBody = append(responseBody, listResponse(list))
+	}
+	responseWriter.Header().Set("Content-Type", "application/json")
+	responseWriter.WriteHeader(200)
+	json.NewEncoder(responseWriter).Encode(responseBody)
+}
+
+func (apiCfg *apiConfig) myListsHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	userData, _ := userFromContext(req.Context())
+	apiCfg.writeLists(responseWriter, userData.ID, true)
+}
+
+// userListsHandler shows a user's public lists, or all of them to the user
+// themselves.
+func (apiCfg *apiConfig) userListsHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	userID, err := uuid.Parse(req.PathValue("userID"))
+	if err != nil {
+		responseWriter.WriteHeader(404)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Error parsing user id."))
+		return
+	}
+
+	viewerID := apiCfg.viewerFromRequest(req)
+	blocked, err := apiCfg.db.IsBlockedEitherWay(context.Background(), database.IsBlockedEitherWayParams{
+		UserID:  viewerID,
+		OtherID: userID,
+	})
+	if err != nil {
+		responseWriter.WriteHeader(500)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Internal Server failed to access database."))
+		return
+	}
+	owner, err := apiCfg.db.GetUserByID(context.Background(), userID)
+	if err != nil || owner.DeletionRequestedAt.Valid || blocked {
+		responseWriter.WriteHeader(404)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("No such user " + userID.String()))
+		return
+	}
+
+	apiCfg.writeLists(responseWriter, owner.ID, owner.ID == viewerID)
+}
+
+// listFromPath loads the list named in the path if viewerID may see it: it
+// is public or theirs, and neither they nor its owner blocked the other. It
+// answers 404 otherwise.
+func (apiCfg *apiConfig) listFromPath(responseWriter http.ResponseWriter, req *http.Request, viewerID uuid.UUID) (database.List, bool) {
+	listID, err := uuid.Parse(req.PathValue("listID"))
+	if err != nil {
+		responseWriter.WriteHeader(404)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Error parsing list id."))
+		return database.List{}, false
+	}
+
+	viewerBlocked := false
+	list, err := apiCfg.db.GetList(context.Background(), listID)
+	if err == nil {
+		viewerBlocked, err = apiCfg.db.IsBlockedEitherWay(context.Background(), database.IsBlockedEitherWayParams{
+			UserID:  viewerID,
+			OtherID: list.OwnerID,
+		})
+	}
+
+	// Someone else's private list looks the same as a missing one.
+	if err != nil || viewerBlocked || (list.IsPrivate && list.OwnerID != viewerID) {
+		responseWriter.WriteHeader(404)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("No such list " + listID.String()))
+		return database.List{}, false
+	}
+	return list, true
+}
+
+// ownListFromPath is listFromPath for changes, which only the owner can
+// make.
+func (apiCfg *apiConfig) ownListFromPath(responseWriter http.ResponseWriter, req *http.Request, userID uuid.UUID) (database.List, bool) {
+	list, ok := apiCfg.listFromPath(responseWriter, req, userID)
+	if !ok {
+		return database.List{}, false
+	}
+	if list.OwnerID != userID {
+		responseWriter.WriteHeader(403)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Only the list's owner can change it."))
+		return database.List{}, false
+	}
+	return list, true
+}
+
+func (apiCfg *apiConfig) getListHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	list, ok := apiCfg.listFromPath(responseWriter, req, apiCfg.viewerFromRequest(req))
+	if !ok {
+		return
+	}
+
+	responseWriter.Header().Set("Content-Type", "application/json")
+	responseWriter.WriteHeader(200)
+	json.NewEncoder(responseWriter).Encode(listResponse(list))
+}
+
+func (apiCfg *apiConfig) updateListHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	userData, _ := userFromContext(req.Context())
+
+	list, ok := apiCfg.ownListFromPath(responseWriter, req, userData.ID)
+	if !ok {
+		return
+	}
+	requestData, ok := decodeListBody(responseWriter, req)
+	if !ok {
+		return
+	}
+
+	updated, err := apiCfg.db.UpdateList(context.Background(), database.UpdateListParams{
+		ID:          list.ID,
+		OwnerID:     userData.ID,
+		Name:        requestData.Name,
+		Description: requestData.Description,
+		IsPrivate:   requestData.Private,
+		UpdatedAt:   time.Now(),
+	})
+	if err != nil {
+		responseWriter.WriteHeader(404)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("No such list " + list.ID.String()))
+		return
+	}
+
+	responseWriter.Header().Set("Content-Type", "application/json")
+	responseWriter.WriteHeader(200)
+	json.NewEncoder(responseWriter).Encode(listResponse(updated))
+}
+
+func (apiCfg *apiConfig) deleteListHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	userData, _ := userFromContext(req.Context())
+
+	list, ok := apiCfg.ownListFromPath(responseWriter, req, userData.ID)
+	if !ok {
+		return
+	}
+
+	_, err := apiCfg.db.DeleteList(context.Background(), database.DeleteListParams{
+		ID:      list.ID,
+		OwnerID: userData.ID,
+	})
+	if err != nil {
+		responseWriter.WriteHeader(404)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("No such list " + list.ID.String()))
+		return
+	}
+	responseWriter.WriteHeader(204)
+}
+
+func (apiCfg *apiConfig) listMembersHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	list, ok := apiCfg.listFromPath(responseWriter, req, apiCfg.viewerFromRequest(req))
+	if !ok {
+		return
+	}
+
+	members, err := apiCfg.db.GetListMembers(context.Background(), list.ID)
+	if err != nil {
+		responseWriter.WriteHeader(500)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Internal Server failed to access database."))
+		return
+	}
+
+	responseBody := []relationResponseBody{}
+	for _, member := range members {
+		responseBody = append(responseBody, relationResponseBody{
+			UserID:    member.UserID.String(),
+			CreatedAt: member.AddedAt.String(),
+		})
+	}
+	responseWriter.Header().Set("Content-Type", "application/json")
+	responseWriter.WriteHeader(200)
+	json.NewEncoder(responseWriter).Encode(responseBody)
+}
+
+func (apiCfg *apiConfig) addListMemberHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	userData, _ := userFromContext(req.Context())
+
+	list, ok := apiCfg.ownListFromPath(responseWriter, req, userData.ID)
+	if !ok {
+		return
+	}
+	userID, err := uuid.Parse(req.PathValue("userID"))
+	if err != nil {
+		responseWriter.WriteHeader(404)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Error parsing user id."))
+		return
+	}
+
+	member, err := apiCfg.db.GetUserByID(context.Background(), userID)
+	if err != nil || member.DeletionRequestedAt.Valid {
+		responseWriter.WriteHeader(404)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("No such user " + userID.String()))
+		return
+	}
+	blocked, err := apiCfg.db.IsBlockedEitherWay(context.Background(), database.IsBlockedEitherWayParams{
+		UserID:  userData.ID,
+		OtherID: member.ID,
+	})
+	if err != nil {
+		responseWriter.WriteHeader(500)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Internal Server failed to access database."))
+		return
+	}
+	if blocked {
+		responseWriter.WriteHeader(403)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("You can't add " + member.ID.String() + " to a list."))
+		return
+	}
+
+	count, err := apiCfg.db.CountListMembers(context.Background(), list.ID)
+	if err != nil {
+		responseWriter.WriteHeader(500)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Internal Server failed to access database."))
+		return
+	}
+	if count >= maxListMembers {
+		responseWriter.WriteHeader(409)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Lists can have at most 500 members."))
+		return
+	}
+
+	// Adding someone twice keeps them where they were.
+	err = apiCfg.db.AddListMember(context.Background(), database.AddListMemberParams{
+		ListID:  list.ID,
+		UserID:  member.ID,
+		AddedAt: time.Now(),
+	})
+	if err != nil {
+		responseWriter.WriteHeader(500)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Unable to add list member."))
+		return
+	}
+	responseWriter.WriteHeader(204)
+}
+
+func (apiCfg *apiConfig) removeListMemberHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	userData, _ := userFromContext(req.Context())
+
+	list, ok := apiCfg.ownListFromPath(responseWriter, req, userData.ID)
+	if !ok {
+		return
+	}
+	userID, err := uuid.Parse(req.PathValue("userID"))
+	if err != nil {
+		responseWriter.WriteHeader(404)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Error parsing user id."))
+		return
+	}
+
+	err = apiCfg.db.RemoveListMember(context.Background(), database.RemoveListMemberParams{
+		ListID: list.ID,
+		UserID: userID,
+	})
+	if err != nil {
+		responseWriter.WriteHeader(500)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Unable to remove list member."))
+		return
+	}
+	responseWriter.WriteHeader(204)
+}
+
+// listChirpsHandler pages through the chirps of a list's members, newest
+// first. Members the viewer blocked, or who blocked the viewer, are skipped,
+// and so are sensitive chirps unless the viewer wants them.
+func (apiCfg *apiConfig) listChirpsHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	type responseBody struct {
+		Chirps     []chirpResponseBody `json:"chirps"`
+		NextCursor string              `json:"next_cursor,omitempty"`
+	}
+
+	viewerID := apiCfg.viewerFromRequest(req)
+	list, ok := apiCfg.listFromPath(responseWriter, req, viewerID)
+	if !ok {
+		return
+	}
+	cursor, err := parsePageCursor(req.URL.Query().Get("cursor"))
+	if err != nil {
+		responseWriter.WriteHeader(400)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte(err.Error()))
+		return
+	}
+	limit := pageLimit(req)
+	includeSensitive, status, err := apiCfg.includeSensitive(req, viewerID)
+	if err != nil {
+		responseWriter.WriteHeader(status)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte(err.Error()))
+		return
+	}
+
+	chirps, err := apiCfg.db.GetListChirps(context.Background(), database.GetListChirpsParams{
+		ListID:           list.ID,
+		ViewerID:         viewerID,
+		IncludeSensitive: includeSensitive,
+		Before:           cursor.Time,
+		BeforeID:         cursor.ID,
+		MaxResults:       limit,
+	})
+	if err != nil {
+		responseWriter.WriteHeader(500)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Internal Server failed to access database."))
+		return
+	}
+
+	responseData := responseBody{Chirps: []chirpResponseBody{}}
+	for _, chirp := range chirps {
+		responseData.Chirps = append(responseData.Chirps, chirpResponse(chirp))
+	}
+	if len(chirps) == int(limit) {
+		last := chirps[len(chirps)-1]
+		responseData.NextCursor = pageCursor{Time: last.CreatedAt, ID: last.ID}.String()
+	}
+
+	responseWriter.Header().Set("Content-Type", "application/json")
+	responseWriter.WriteHeader(200)
+	json.NewEncoder(responseWriter).Encode(responseData)
+}
@@ -1,12 +1,24 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/anantashahane/Chirpy/internal/auth"
 	"github.com/anantashahane/Chirpy/internal/database"
+	"github.com/anantashahane/Chirpy/internal/egress"
+	"github.com/anantashahane/Chirpy/internal/jobs"
+	"github.com/anantashahane/Chirpy/internal/oidc"
+	"github.com/anantashahane/Chirpy/internal/pubsub"
+	"github.com/anantashahane/Chirpy/internal/ratelimit"
+	"github.com/anantashahane/Chirpy/internal/ttlcache"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 )
@@ -26,9 +38,61 @@ func main() {
 		os.Exit(4)
 	}
 	cfg.db = database.New(db)
+	cfg.dbConn = db
 	cfg.platform = os.Getenv("PLATFORM")
 	cfg.secret = secret
 	cfg.polkaKey = os.Getenv("POKLA_KEY")
+	for _, polkaSecret := range strings.Split(os.Getenv("POLKA_WEBHOOK_SECRETS"), ",") {
+		if polkaSecret = strings.TrimSpace(polkaSecret); polkaSecret != "" {
+			cfg.polkaSecrets = append(cfg.polkaSecrets, polkaSecret)
+		}
+	}
+	cfg.polkaTolerance = durationFromEnv("POLKA_SIGNATURE_TOLERANCE", 5*time.Minute)
+
+	//MARK:- Password hashing policy.
+	passwordPolicy := auth.DefaultPasswordPolicy()
+	if algorithm := os.Getenv("PASSWORD_ALGORITHM"); algorithm != "" {
+		passwordPolicy.Algorithm = algorithm
+	}
+	passwordPolicy.BcryptCost = intFromEnv("BCRYPT_COST", passwordPolicy.BcryptCost)
+	passwordPolicy.Argon2Time = uint32(intFromEnv("ARGON2_TIME", int(passwordPolicy.Argon2Time)))
+	passwordPolicy.Argon2MemoryKiB = uint32(intFromEnv("ARGON2_MEMORY_KIB", int(passwordPolicy.Argon2MemoryKiB)))
+	passwordPolicy.Argon2Threads = uint8(intFromEnv("ARGON2_THREADS", int(passwordPolicy.Argon2Threads)))
+	if err := auth.SetPasswordPolicy(passwordPolicy); err != nil {
+		fmt.Println("Invalid password policy, " + err.Error())
+		os.Exit(5)
+	}
+
+	cfg.chirpLimiter = ratelimit.New(time.Hour)
+	cfg.jobRunner = jobs.NewRunner(cfg.db)
+	cfg.registerJobs()
+	cfg.deletionGracePeriod = durationFromEnv("ACCOUNT_DELETION_GRACE_PERIOD", 30*24*time.Hour)
+	cfg.exportDir = os.Getenv("EXPORT_DIR")
+	if cfg.exportDir == "" {
+		cfg.exportDir = filepath.Join(os.TempDir(), "chirpy-exports")
+	}
+	cfg.exportTTL = durationFromEnv("EXPORT_TTL", 48*time.Hour)
+	cfg.trashRetention = durationFromEnv("CHIRP_TRASH_RETENTION", 30*24*time.Hour)
+	// Webhook URLs come from users, so deliveries must not reach internal
+	// addresses.
+	cfg.webhookClient = egress.NewClient(webhookTimeout)
+	cfg.webhookMaxAttempts = int32(intFromEnv("WEBHOOK_MAX_ATTEMPTS", 8))
+	cfg.streamHub = pubsub.NewHub()
+	cfg.trendCache = ttlcache.New[trendSnapshot](trendCacheTTL)
+
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(&cfg, os.Args[1:]))
+	}
+
+	//MARK:- Optional OpenID Connect sign in.
+	if issuer := os.Getenv("OIDC_ISSUER"); issuer != "" {
+		provider, err := oidc.Discover(context.Background(), issuer, os.Getenv("OIDC_CLIENT_ID"), os.Getenv("OIDC_CLIENT_SECRET"), os.Getenv("OIDC_REDIRECT_URL"))
+		if err != nil {
+			fmt.Println("OpenID Connect disabled, " + err.Error())
+		} else {
+			cfg.oidcProvider = provider
+		}
+	}
 
 	serveMux := http.NewServeMux()
 	server := http.Server{}
@@ -36,43 +100,214 @@ func main() {
 	server.Addr = ":8080"
 	server.Handler = serveMux
 
-	serveMux.HandleFunc("GET /admin/metrics/", apiHandler(cfg.metricsHandler, "/admin/"))
-	serveMux.HandleFunc("POST /admin/reset", apiHandler(cfg.resetHandler, "/admin/"))
+	serveMux.HandleFunc("GET /admin/metrics/", apiHandler(cfg.requireRole(cfg.metricsHandler, roleAdmin), "/admin/"))
+	serveMux.HandleFunc("POST /admin/reset", apiHandler(cfg.requireRole(cfg.resetHandler, roleAdmin), "/admin/"))
+	serveMux.HandleFunc("PUT /admin/users/{userID}/role", apiHandler(cfg.requireRole(cfg.setRoleHandler, roleAdmin), "/admin/"))
+	serveMux.HandleFunc("GET /admin/jobs", apiHandler(cfg.requireRole(cfg.listJobsHandler, roleAdmin), "/admin/"))
+	serveMux.HandleFunc("GET /admin/webhooks/polka/events", apiHandler(cfg.requireRole(cfg.listWebhookEventsHandler, roleAdmin), "/admin/"))
+	serveMux.HandleFunc("POST /admin/webhooks/polka/events/{eventID}/replay", apiHandler(cfg.requireRole(cfg.replayWebhookEventHandler, roleAdmin), "/admin/"))
 	serveMux.HandleFunc("GET /api/healthz/", apiHandler(healthHandler, "/api/"))
 
 	serveMux.Handle("/app/", cfg.middlewareMetricsInc(http.StripPrefix("/app/", http.FileServer(http.Dir(".")))))
 
 	serveMux.HandleFunc("POST /api/users", apiHandler(cfg.createUserHandler, "/api/"))
 	serveMux.HandleFunc("PUT /api/users", apiHandler(cfg.handleUserPasswordChange, "/api/"))
+	serveMux.HandleFunc("DELETE /api/users/me", apiHandler(cfg.requireRole(cfg.deleteOwnAccountHandler), "/api/"))
+	serveMux.HandleFunc("POST /api/users/me/export", apiHandler(cfg.requireRole(cfg.requestDataExportHandler), "/api/"))
+	serveMux.HandleFunc("GET /api/users/me/export/{exportID}", apiHandler(cfg.requireRole(cfg.getDataExportHandler), "/api/"))
+	serveMux.HandleFunc("PUT /api/users/me/pins", apiHandler(cfg.requireRole(cfg.reorderPinsHandler), "/api/"))
+	serveMux.HandleFunc("POST /api/users/me/pins/{chirpID}", apiHandler(cfg.requireRole(cfg.pinChirpHandler), "/api/"))
+	serveMux.HandleFunc("DELETE /api/users/me/pins/{chirpID}", apiHandler(cfg.requireRole(cfg.unpinChirpHandler), "/api/"))
+	serveMux.HandleFunc("GET /api/users/{userID}/chirps", apiHandler(cfg.userChirpsHandler, "/api/"))
 	serveMux.HandleFunc("POST /api/login", apiHandler(cfg.loginUserHandler, "/api/"))
 	serveMux.HandleFunc("POST /api/refresh", apiHandler(cfg.handleRefresh, "/api/"))
 	serveMux.HandleFunc("POST /api/revoke", apiHandler(cfg.handleRevoke, "/api/"))
+	serveMux.HandleFunc("GET /api/oidc/login", apiHandler(cfg.oidcLoginHandler, "/api/"))
+	serveMux.HandleFunc("GET /api/oidc/callback", apiHandler(cfg.oidcCallbackHandler, "/api/"))
 
-	serveMux.HandleFunc("POST /api/chirps", apiHandler(cfg.createChirpHandler, "/api/"))
+	serveMux.HandleFunc("POST /api/chirps", apiHandler(cfg.requireRole(cfg.createChirpHandler), "/api/"))
 	serveMux.HandleFunc("GET /api/chirps/", apiHandler(cfg.getAllChirpsHandler, "/api/"))
+	serveMux.HandleFunc("GET /api/stream", apiHandler(cfg.streamHandler, "/api/"))
+	serveMux.HandleFunc("GET /api/ws", apiHandler(cfg.requireRole(cfg.websocketHandler), "/api/"))
+	serveMux.HandleFunc("GET /api/chirps/analytics", apiHandler(cfg.requireRole(cfg.chirpAnalyticsHandler), "/api/"))
+	serveMux.HandleFunc("GET /api/chirps/scheduled", apiHandler(cfg.requireRole(cfg.getScheduledChirpsHandler), "/api/"))
+	serveMux.HandleFunc("POST /api/chirps/scheduled/{chirpID}/cancel", apiHandler(cfg.requireRole(cfg.cancelScheduledChirpHandler), "/api/"))
+	serveMux.HandleFunc("GET /api/chirps/trash", apiHandler(cfg.requireRole(cfg.getTrashHandler), "/api/"))
+	serveMux.HandleFunc("POST /api/chirps/trash/{chirpID}/restore", apiHandler(cfg.requireRole(cfg.restoreChirpHandler), "/api/"))
 	serveMux.HandleFunc("GET /api/chirps/{chirpID}", apiHandler(cfg.handleGetChirpByID, "/api/"))
-	serveMux.HandleFunc("DELETE /api/chirps/{chirpID}", apiHandler(cfg.deleteChirpHandler, "/api/"))
+	serveMux.HandleFunc("PUT /api/chirps/{chirpID}", apiHandler(cfg.requireRole(cfg.updateChirpHandler), "/api/"))
+	serveMux.HandleFunc("POST /api/chirps/{chirpID}/bookmark", apiHandler(cfg.requireRole(cfg.bookmarkChirpHandler), "/api/"))
+	serveMux.HandleFunc("DELETE /api/chirps/{chirpID}/bookmark", apiHandler(cfg.requireRole(cfg.removeBookmarkHandler), "/api/"))
+	serveMux.HandleFunc("GET /api/bookmarks", apiHandler(cfg.requireRole(cfg.listBookmarksHandler), "/api/"))
+	serveMux.HandleFunc("POST /api/users/{userID}/block", apiHandler(cfg.requireRole(cfg.blockUserHandler), "/api/"))
+	serveMux.HandleFunc("DELETE /api/users/{userID}/block", apiHandler(cfg.requireRole(cfg.unblockUserHandler), "/api/"))
+	serveMux.HandleFunc("GET /api/users/me/blocks", apiHandler(cfg.requireRole(cfg.listBlocksHandler), "/api/"))
+	serveMux.HandleFunc("POST /api/users/{userID}/mute", apiHandler(cfg.requireRole(cfg.muteUserHandler), "/api/"))
+	serveMux.HandleFunc("DELETE /api/users/{userID}/mute", apiHandler(cfg.requireRole(cfg.unmuteUserHandler), "/api/"))
+	serveMux.HandleFunc("GET /api/users/me/mutes", apiHandler(cfg.requireRole(cfg.listMutesHandler), "/api/"))
+	serveMux.HandleFunc("GET /api/users/me/preferences", apiHandler(cfg.requireRole(cfg.getPreferencesHandler), "/api/"))
+	serveMux.HandleFunc("PUT /api/users/me/preferences", apiHandler(cfg.requireRole(cfg.updatePreferencesHandler), "/api/"))
+	serveMux.HandleFunc("GET /api/notifications", apiHandler(cfg.requireRole(cfg.listNotificationsHandler), "/api/"))
+	serveMux.HandleFunc("GET /api/notifications/unread", apiHandler(cfg.requireRole(cfg.unreadNotificationsHandler), "/api/"))
+	serveMux.HandleFunc("POST /api/notifications/{notificationID}/read", apiHandler(cfg.requireRole(cfg.markNotificationReadHandler), "/api/"))
+	serveMux.HandleFunc("POST /api/notifications/read", apiHandler(cfg.requireRole(cfg.markAllNotificationsReadHandler), "/api/"))
+	serveMux.HandleFunc("GET /api/notifications/preferences", apiHandler(cfg.requireRole(cfg.getNotificationPreferencesHandler), "/api/"))
+	serveMux.HandleFunc("PUT /api/notifications/preferences", apiHandler(cfg.requireRole(cfg.updateNotificationPreferencesHandler), "/api/"))
+	serveMux.HandleFunc("POST /api/conversations", apiHandler(cfg.requireRole(cfg.createConversationHandler), "/api/"))
+	serveMux.HandleFunc("GET /api/conversations", apiHandler(cfg.requireRole(cfg.listConversationsHandler), "/api/"))
+	serveMux.HandleFunc("GET /api/conversations/{conversationID}/messages", apiHandler(cfg.requireRole(cfg.listMessagesHandler), "/api/"))
+	serveMux.HandleFunc("POST /api/conversations/{conversationID}/messages", apiHandler(cfg.requireRole(cfg.sendMessageHandler), "/api/"))
+	serveMux.HandleFunc("POST /api/conversations/{conversationID}/read", apiHandler(cfg.requireRole(cfg.markConversationReadHandler), "/api/"))
+	serveMux.HandleFunc("POST /api/lists", apiHandler(cfg.requireRole(cfg.createListHandler), "/api/"))
+	serveMux.HandleFunc("GET /api/lists", apiHandler(cfg.requireRole(cfg.myListsHandler), "/api/"))
+	serveMux.HandleFunc("GET /api/lists/{listID}", apiHandler(cfg.getListHandler, "/api/"))
+	serveMux.HandleFunc("PUT /api/lists/{listID}", apiHandler(cfg.requireRole(cfg.updateListHandler), "/api/"))
+	serveMux.HandleFunc("DELETE /api/lists/{listID}", apiHandler(cfg.requireRole(cfg.deleteListHandler), "/api/"))
+	serveMux.HandleFunc("GET /api/lists/{listID}/members", apiHandler(cfg.listMembersHandler, "/api/"))
+	serveMux.HandleFunc("POST /api/lists/{listID}/members/{userID}", apiHandler(cfg.requireRole(cfg.addListMemberHandler), "/api/"))
+	serveMux.HandleFunc("DELETE /api/lists/{listID}/members/{userID}", apiHandler(cfg.requireRole(cfg.removeListMemberHandler), "/api/"))
+	serveMux.HandleFunc("GET /api/lists/{listID}/chirps", apiHandler(cfg.listChirpsHandler, "/api/"))
+	serveMux.HandleFunc("GET /api/users/{userID}/lists", apiHandler(cfg.userListsHandler, "/api/"))
+	serveMux.HandleFunc("GET /api/trends", apiHandler(cfg.trendsHandler, "/api/"))
+	serveMux.HandleFunc("DELETE /api/chirps/{chirpID}", apiHandler(cfg.requireRole(cfg.deleteChirpHandler), "/api/"))
+
+	serveMux.HandleFunc("POST /api/drafts", apiHandler(cfg.requireRole(cfg.createDraftHandler), "/api/"))
+	serveMux.HandleFunc("GET /api/drafts", apiHandler(cfg.requireRole(cfg.listDraftsHandler), "/api/"))
+	serveMux.HandleFunc("GET /api/drafts/{draftID}", apiHandler(cfg.requireRole(cfg.getDraftHandler), "/api/"))
+	serveMux.HandleFunc("PUT /api/drafts/{draftID}", apiHandler(cfg.requireRole(cfg.updateDraftHandler), "/api/"))
+	serveMux.HandleFunc("DELETE /api/drafts/{draftID}", apiHandler(cfg.requireRole(cfg.deleteDraftHandler), "/api/"))
+	serveMux.HandleFunc("POST /api/drafts/{draftID}/publish", apiHandler(cfg.requireRole(cfg.publishDraftHandler), "/api/"))
+
+	serveMux.HandleFunc("POST /api/webhooks", apiHandler(cfg.requireRole(cfg.createWebhookEndpointHandler), "/api/"))
+	serveMux.HandleFunc("GET /api/webhooks", apiHandler(cfg.requireRole(cfg.listWebhookEndpointsHandler), "/api/"))
+	serveMux.HandleFunc("DELETE /api/webhooks/{webhookID}", apiHandler(cfg.requireRole(cfg.deleteWebhookEndpointHandler), "/api/"))
+	serveMux.HandleFunc("GET /api/webhooks/{webhookID}/deliveries", apiHandler(cfg.requireRole(cfg.listWebhookDeliveriesHandler), "/api/"))
 
 	serveMux.HandleFunc("POST /api/polka/webhooks", apiHandler(cfg.upgradeUserHandler, "/api/polka/webhooks"))
 
 	fmt.Println("Listening on")
 	fmt.Println("\tPOST admin/reset")
+	fmt.Println("\tPUT admin/users/{userID}/role")
+	fmt.Println("\tGET admin/jobs")
+	fmt.Println("\tGET admin/webhooks/polka/events")
+	fmt.Println("\tPOST admin/webhooks/polka/events/{eventID}/replay")
 	fmt.Println()
 	fmt.Println("\tGET /app")
 	fmt.Println("\tGET api/healthz")
 	fmt.Println("\tGET api/metrics")
 	fmt.Println("\tPOST api/users")
 	fmt.Println("\tPUT api/users")
+	fmt.Println("\tDELETE api/users/me")
+	fmt.Println("\tPOST api/users/me/export")
+	fmt.Println("\tGET api/users/me/export/{exportID}")
+	fmt.Println("\tPUT api/users/me/pins")
+	fmt.Println("\tPOST api/users/me/pins/{chirpID}")
+	fmt.Println("\tDELETE api/users/me/pins/{chirpID}")
+	fmt.Println("\tGET api/users/{userID}/chirps")
 	fmt.Println("\tPOST api/login")
 	fmt.Println("\tGET api/chirps/[{chripID}]")
 	fmt.Println("\tGET api/chirps")
+	fmt.Println("\tGET api/stream")
+	fmt.Println("\tGET api/ws")
+	fmt.Println("\tPUT api/chirps/{chirpID}")
 	fmt.Println("\tDELETE api/chirps/{chirpID}")
+	fmt.Println("\tPOST api/chirps/{chirpID}/bookmark")
+	fmt.Println("\tDELETE api/chirps/{chirpID}/bookmark")
+	fmt.Println("\tGET api/bookmarks")
+	fmt.Println("\tPOST api/users/{userID}/block")
+	fmt.Println("\tDELETE api/users/{userID}/block")
+	fmt.Println("\tGET api/users/me/blocks")
+	fmt.Println("\tPOST api/users/{userID}/mute")
+	fmt.Println("\tDELETE api/users/{userID}/mute")
+	fmt.Println("\tGET api/users/me/mutes")
+	fmt.Println("\tGET api/users/me/preferences")
+	fmt.Println("\tPUT api/users/me/preferences")
+	fmt.Println("\tGET api/notifications")
+	fmt.Println("\tGET api/notifications/unread")
+	fmt.Println("\tPOST api/notifications/{notificationID}/read")
+	fmt.Println("\tPOST api/notifications/read")
+	fmt.Println("\tGET api/notifications/preferences")
+	fmt.Println("\tPUT api/notifications/preferences")
+	fmt.Println("\tPOST api/conversations")
+	fmt.Println("\tGET api/conversations")
+	fmt.Println("\tGET api/conversations/{conversationID}/messages")
+	fmt.Println("\tPOST api/conversations/{conversationID}/messages")
+	fmt.Println("\tPOST api/conversations/{conversationID}/read")
+	fmt.Println("\tPOST api/lists")
+	fmt.Println("\tGET api/lists")
+	fmt.Println("\tGET api/lists/{listID}")
+	fmt.Println("\tPUT api/lists/{listID}")
+	fmt.Println("\tDELETE api/lists/{listID}")
+	fmt.Println("\tGET api/lists/{listID}/members")
+	fmt.Println("\tPOST api/lists/{listID}/members/{userID}")
+	fmt.Println("\tDELETE api/lists/{listID}/members/{userID}")
+	fmt.Println("\tGET api/lists/{listID}/chirps")
+	fmt.Println("\tGET api/users/{userID}/lists")
+	fmt.Println("\tGET api/trends")
+	fmt.Println("\tGET api/chirps/analytics")
+	fmt.Println("\tGET api/chirps/scheduled")
+	fmt.Println("\tPOST api/chirps/scheduled/{chirpID}/cancel")
+	fmt.Println("\tGET api/chirps/trash")
+	fmt.Println("\tPOST api/chirps/trash/{chirpID}/restore")
 	fmt.Println("\tPost api/refresh")
 	fmt.Println("\tPost api/revoke")
+	fmt.Println("\tGET api/oidc/login")
+	fmt.Println("\tGET api/oidc/callback")
+	fmt.Println("\tPOST api/drafts")
+	fmt.Println("\tGET api/drafts")
+	fmt.Println("\tGET api/drafts/{draftID}")
+	fmt.Println("\tPUT api/drafts/{draftID}")
+	fmt.Println("\tDELETE api/drafts/{draftID}")
+	fmt.Println("\tPOST api/drafts/{draftID}/publish")
+	fmt.Println("\tPOST api/webhooks")
+	fmt.Println("\tGET api/webhooks")
+	fmt.Println("\tDELETE api/webhooks/{webhookID}")
+	fmt.Println("\tGET api/webhooks/{webhookID}/deliveries")
 	fmt.Println("\tPost api/polka/webhooks")
 
+	go runEvery(context.Background(), time.Hour, cfg.purgeDeletedAccounts)
+	go runEvery(context.Background(), time.Hour, cfg.purgeExpiredExports)
+	go runEvery(context.Background(), 15*time.Minute, cfg.expireLapsedSubscriptions)
+	go runEvery(context.Background(), 5*time.Second, cfg.deliverWebhooks)
+	go runEvery(context.Background(), 2*time.Second, cfg.runJobs)
+	go runEvery(context.Background(), time.Hour, cfg.purgeFinishedJobs)
+	go runEvery(context.Background(), time.Hour, cfg.purgeTrashedChirps)
+	go runEvery(context.Background(), time.Hour, cfg.purgeStreamEvents)
+	go runEvery(context.Background(), trendRefreshInterval, cfg.refreshTrends)
+	go cfg.listenStreamEvents(context.Background(), dbURL)
+
 	err = server.ListenAndServe()
 	if err != nil {
 		os.Exit(1)
 	}
 }
+
+// durationFromEnv parses a time.Duration such as "720h" from the environment,
+// falling back when unset or malformed.
+func durationFromEnv(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		fmt.Printf("Ignoring %s=%q, %s\n", key, value, err.Error())
+		return fallback
+	}
+	return duration
+}
+
+// intFromEnv parses an integer from the environment, falling back when unset
+// or malformed.
+func intFromEnv(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		fmt.Printf("Ignoring %s=%q, %s\n", key, value, err.Error())
+		return fallback
+	}
+	return number
+}
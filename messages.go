@@ -0,0 +1,426 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/anantashahane/Chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	// Everyone in a conversation, its creator included.
+	maxConversationMembers = 8
+	maxMessageLength       = 2000
+)
+
+type conversationResponseBody struct {
+	ID          string   `json:"id"`
+	CreatedAt   string   `json:"created_at"`
+	UpdatedAt   string   `json:"updated_at"`
+	MemberIDs   []string `json:"member_ids"`
+	UnreadCount int64    `json:"unread_count"`
+}
+
+type messageResponseBody struct {
+	ID             string `json:"id"`
+	ConversationID string `json:"conversation_id"`
+	SenderID       string `json:"sender_id"`
+	Body           string `json:"body"`
+	CreatedAt      string `json:"created_at"`
+}
+
+func messageResponse(message database.Message) messageResponseBody {
+	return messageResponseBody{
+		ID:             message.ID.String(),
+		ConversationID: message.ConversationID.String(),
+		SenderID:       message.SenderID.String(),
+		Body:           message.Body,
+		CreatedAt:      message.CreatedAt.String(),
+	}
+}
+
+// conversationTopic is the private stream of a conversation's new messages.
+func conversationTopic(conversationID uuid.UUID) string {
+	return "conversation:" + conversationID.String()
+}
+
+// createConversationHandler starts a conversation between the caller and the
+// listed users. Asking for a one-to-one conversation that already exists
+// returns that one.
+func (apiCfg *apiConfig) createConversationHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	type requestBody struct {
+		UserIDs []uuid.UUID `json:"user_ids"`
+	}
+
+	userData, _ := userFromContext(req.Context())
+
+	decoder := json.NewDecoder(req.Body)
+	decoder.DisallowUnknownFields()
+	requestData := requestBody{}
+	if err := decoder.Decode(&requestData); err != nil {
+		responseWriter.WriteHeader(400)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Json Decode failed."))
+		return
+	}
+	if len(requestData.UserIDs) == 0 || len(requestData.UserIDs) >= maxConversationMembers {
+		responseWriter.WriteHeader(400)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("A conversation needs between 2 and 8 members, you included."))
+		return
+	}
+	unique := slices.Clone(requestData.UserIDs)
+	slices.SortFunc(unique, func(a, b uuid.UUID) int { return slices.Compare(a[:], b[:]) })
+	if len(slices.Compact(unique)) != len(requestData.UserIDs) || slices.Contains(unique, userData.ID) {
+		responseWriter.WriteHeader(400)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("List each other member once, without yourself."))
+		return
+	}
+
+	for _, memberID := range requestData.UserIDs {
+		member, err := apiCfg.db.GetUserByID(context.Background(), memberID)
+		if err != nil || member.DeletionRequestedAt.Valid {
+			responseWriter.WriteHeader(404)
+			responseWriter.Header().Set("Content-Type", "plain/text")
+			responseWriter.Write([]byte("No such user " + memberID.String()))
+			return
+		}
+		blocked, err := apiCfg.db.IsBlockedEitherWay(context.Background(), database.IsBlockedEitherWayParams{
+			UserID:  userData.ID,
+			OtherID: memberID,
+		})
+		if err != nil {
+			responseWriter.WriteHeader(500)
+			responseWriter.Header().Set("Content-Type", "plain/text")
+			responseWriter.Write([]byte("Internal Server failed to access database."))
+			return
+		}
+		if blocked {
+			responseWriter.WriteHeader(403)
+			responseWriter.Header().Set("Content-Type", "plain/text")
+			responseWriter.Write([]byte("You can't message " + memberID.String() + "."))
+			return
+		}
+	}
+
+	// Two people share one direct conversation. Starting it takes a lock on
+	// the pair, so requests racing to start it find the first one's.
+	var conversation database.Conversation
+	found := false
+	err := apiCfg.inTx(context.Background(), func(q *database.Queries) error {
+		if len(requestData.UserIDs) == 1 {
+			pair := database.LockDirectConversationParams{UserID: userData.ID, OtherID: requestData.UserIDs[0]}
+			if err := q.LockDirectConversation(context.Background(), pair); err != nil {
+				return err
+			}
+			existing, err := q.FindDirectConversation(context.Background(), database.FindDirectConversationParams(pair))
+			if err == nil {
+				conversation, found = existing, true
+				return nil
+			}
+			if !errors.Is(err, sql.ErrNoRows) {
+				return err
+			}
+		}
+
+		var err error
+		conversation, err = q.CreateConversation(context.Background(), database.CreateConversationParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+			CreatedBy: uuid.NullUUID{UUID: userData.ID, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+		for _, memberID := range append([]uuid.UUID{userData.ID}, requestData.UserIDs...) {
+			err = q.AddConversationMember(context.Background(), database.AddConversationMemberParams{
+				ConversationID: conversation.ID,
+				UserID:         memberID,
+				JoinedAt:       conversation.CreatedAt,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		responseWriter.WriteHeader(500)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Unable to start conversation."))
+		return
+	}
+
+	if found {
+		apiCfg.writeConversation(responseWriter, 200, conversation, userData.ID)
+		return
+	}
+	apiCfg.writeConversation(responseWriter, 201, conversation, userData.ID)
+}
+
+// writeConversation answers with conversation and its members. The caller
+// has just created or found it, so there is nothing unread yet to count.
+func (apiCfg *apiConfig) writeConversation(responseWriter http.ResponseWriter, status int, conversation database.Conversation, userID uuid.UUID) {
+	members, err := apiCfg.db.GetConversationMembers(context.Background(), conversation.ID)
+	if err != nil {
+		responseWriter.WriteHeader(500)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Internal Server failed to access database."))
+		return
+	}
+
+	responseData := conversationResponseBody{
+		ID:        conversation.ID.String(),
+		CreatedAt: conversation.CreatedAt.String(),
+		UpdatedAt: conversation.UpdatedAt.String(),
+		MemberIDs: []string{},
+	}
+	for _, member := range members {
+		responseData.MemberIDs = append(responseData.MemberIDs, member.UserID.String())
+	}
+	responseWriter.Header().Set("Content-Type", "application/json")
+	responseWriter.WriteHeader(status)
+	json.NewEncoder(responseWriter).Encode(responseData)
+}
+
+// listConversationsHandler pages through the caller's conversations, the
+// most recently active first.
+func (apiCfg *apiConfig) listConversationsHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	type responseBody struct {
+		Conversations []conversationResponseBody `json:"conversations"`
+		NextCursor    string                     `json:"next_cursor,omitempty"`
+	}
+
+	userData, _ := userFromContext(req.Context())
+
+	cursor, err := parsePageCursor(req.URL.Query().Get("cursor"))
+	if err != nil {
+		responseWriter.WriteHeader(400)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte(err.Error()))
+		return
+	}
+	limit := pageLimit(req)
+
+	conversations, err := apiCfg.db.ListConversations(context.Background(), database.ListConversationsParams{
+		UserID:     userData.ID,
+		Before:     cursor.Time,
+		BeforeID:   cursor.ID,
+		MaxResults: limit,
+	})
+	if err != nil {
+		responseWriter.WriteHeader(500)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Internal Server failed to access database."))
+		return
+	}
+
+	responseData := responseBody{Conversations: []conversationResponseBody{}}
+	for _, conversation := range conversations {
+		listed := conversationResponseBody{
+			ID:          conversation.ID.String(),
+			CreatedAt:   conversation.CreatedAt.String(),
+			UpdatedAt:   conversation.UpdatedAt.String(),
+			MemberIDs:   []string{},
+			UnreadCount: conversation.UnreadCount,
+		}
+		for _, memberID := range conversation.MemberIds {
+			listed.MemberIDs = append(listed.MemberIDs, memberID.String())
+		}
+		responseData.Conversations = append(responseData.Conversations, listed)
+	}
+	if len(conversations) == int(limit) {
+		last := conversations[len(conversations)-1]
+		responseData.NextCursor = pageCursor{Time: last.UpdatedAt, ID: last.ID}.String()
+	}
+
+	responseWriter.Header().Set("Content-Type", "application/json")
+	responseWriter.WriteHeader(200)
+	json.NewEncoder(responseWriter).Encode(responseData)
+}
+
+// conversationFromPath loads the conversation named in the path if the
+// caller is in it, answering 404 otherwise.
+func (apiCfg *apiConfig) conversationFromPath(responseWriter http.ResponseWriter, req *http.Request, userID uuid.UUID) (database.Conversation, bool) {
+	conversationID, err := uuid.Parse(req.PathValue("conversationID"))
+	if err != nil {
+		responseWriter.WriteHeader(404)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Error parsing conversation id."))
+		return database.Conversation{}, false
+	}
+
+	conversation, err := apiCfg.db.GetConversationForMember(context.Background(), database.GetConversationForMemberParams{
+		ID:     conversationID,
+		UserID: userID,
+	})
+	if err != nil {
+		responseWriter.WriteHeader(404)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("No such conversation " + conversationID.String()))
+		return database.Conversation{}, false
+	}
+	return conversation, true
+}
+
+// listMessagesHandler pages through a conversation, newest first. Messages
+// from members the caller blocked, or who blocked the caller, are left out.
+func (apiCfg *apiConfig) listMessagesHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	type responseBody struct {
+		Messages   []messageResponseBody `json:"messages"`
+		NextCursor string                `json:"next_cursor,omitempty"`
+	}
+
+	userData, _ := userFromContext(req.Context())
+
+	conversation, ok := apiCfg.conversationFromPath(responseWriter, req, userData.ID)
+	if !ok {
+		return
+	}
+	cursor, err := parsePageCursor(req.URL.Query().Get("cursor"))
+	if err != nil {
+		responseWriter.WriteHeader(400)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte(err.Error()))
+		return
+	}
+	limit := pageLimit(req)
+
+	messages, err := apiCfg.db.GetMessages(context.Background(), database.GetMessagesParams{
+		ConversationID: conversation.ID,
+		ViewerID:       userData.ID,
+		Before:         cursor.Time,
+		BeforeID:       cursor.ID,
+		MaxResults:     limit,
+	})
+	if err != nil {
+		responseWriter.WriteHeader(500)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Internal Server failed to access database."))
+		return
+	}
+
+	responseData := responseBody{Messages: []messageResponseBody{}}
+	for _, message := range messages {
+		responseData.Messages = append(responseData.Messages, messageResponse(message))
+	}
+	if len(messages) == int(limit) {
+		last := messages[len(messages)-1]
+		responseData.NextCursor = pageCursor{Time: last.CreatedAt, ID: last.ID}.String()
+	}
+
+	responseWriter.Header().Set("Content-Type", "application/json")
+	responseWriter.WriteHeader(200)
+	json.NewEncoder(responseWriter).Encode(responseData)
+}
+
+// sendMessageHandler posts to a conversation. Nobody can send to a
+// conversation with a member they blocked or were blocked by.
+func (apiCfg *apiConfig) sendMessageHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	type requestBody struct {
+		Body string `json:"body"`
+	}
+
+	userData, _ := userFromContext(req.Context())
+
+	conversation, ok := apiCfg.conversationFromPath(responseWriter, req, userData.ID)
+	if !ok {
+		return
+	}
+
+	decoder := json.NewDecoder(req.Body)
+	decoder.DisallowUnknownFields()
+	requestData := requestBody{}
+	if err := decoder.Decode(&requestData); err != nil {
+		responseWriter.WriteHeader(400)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Json Decode failed."))
+		return
+	}
+	if requestData.Body == "" || !validateChirp(requestData.Body, maxMessageLength) {
+		responseWriter.WriteHeader(400)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Messages need between 1 and 2000 characters."))
+		return
+	}
+
+	blocked, err := apiCfg.db.IsBlockedInConversation(context.Background(), database.IsBlockedInConversationParams{
+		UserID:         userData.ID,
+		ConversationID: conversation.ID,
+	})
+	if err != nil {
+		responseWriter.WriteHeader(500)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Internal Server failed to access database."))
+		return
+	}
+	if blocked {
+		responseWriter.WriteHeader(403)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("You can't message this conversation."))
+		return
+	}
+
+	var message database.Message
+	err = apiCfg.inTx(context.Background(), func(q *database.Queries) error {
+		var err error
+		message, err = q.CreateMessage(context.Background(), database.CreateMessageParams{
+			ID:             uuid.New(),
+			CreatedAt:      time.Now(),
+			ConversationID: conversation.ID,
+			SenderID:       userData.ID,
+			Body:           requestData.Body,
+		})
+		if err != nil {
+			return err
+		}
+		err = q.TouchConversation(context.Background(), database.TouchConversationParams{
+			ID:        conversation.ID,
+			UpdatedAt: message.CreatedAt,
+		})
+		if err != nil {
+			return err
+		}
+		return recordStreamEvent(context.Background(), q, "message.created", userData.ID, []string{conversationTopic(conversation.ID)}, messageResponse(message))
+	})
+	if err != nil {
+		responseWriter.WriteHeader(500)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Unable to send message."))
+		return
+	}
+
+	responseWriter.Header().Set("Content-Type", "application/json")
+	responseWriter.WriteHeader(201)
+	json.NewEncoder(responseWriter).Encode(messageResponse(message))
+}
+
+func (apiCfg *apiConfig) markConversationReadHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	userData, _ := userFromContext(req.Context())
+
+	conversation, ok := apiCfg.conversationFromPath(responseWriter, req, userData.ID)
+	if !ok {
+		return
+	}
+
+	err := apiCfg.db.MarkConversationRead(context.Background(), database.MarkConversationReadParams{
+		ConversationID: conversation.ID,
+		UserID:         userData.ID,
+		LastReadAt:     sql.NullTime{Time: time.Now(), Valid: true},
+	})
+	if err != nil {
+		responseWriter.WriteHeader(500)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Unable to mark conversation read."))
+		return
+	}
+	responseWriter.WriteHeader(204)
+}
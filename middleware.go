@@ -1,20 +1,58 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"net/http"
+	"slices"
 	"sync/atomic"
+	"time"
 
+	"github.com/anantashahane/Chirpy/internal/auth"
 	"github.com/anantashahane/Chirpy/internal/database"
+	"github.com/anantashahane/Chirpy/internal/jobs"
+	"github.com/anantashahane/Chirpy/internal/oidc"
+	"github.com/anantashahane/Chirpy/internal/pubsub"
+	"github.com/anantashahane/Chirpy/internal/ratelimit"
+	"github.com/anantashahane/Chirpy/internal/ttlcache"
+	"github.com/google/uuid"
 )
 
 type apiConfig struct {
 	fileServerHits atomic.Int32
 	db             *database.Queries
+	dbConn         *sql.DB
 	platform       string
 	secret         string
 	polkaKey       string
+	polkaSecrets   []string
+	polkaTolerance time.Duration
+	oidcProvider   *oidc.Provider
+	chirpLimiter   *ratelimit.Limiter
+	jobRunner      *jobs.Runner
+
+	deletionGracePeriod time.Duration
+	exportDir           string
+	exportTTL           time.Duration
+	trashRetention      time.Duration
+
+	webhookClient      *http.Client
+	webhookMaxAttempts int32
+
+	streamHub  *pubsub.Hub
+	trendCache *ttlcache.Cache[trendSnapshot]
 }
 
+// Roles stored in users.role.
+const (
+	roleUser  = "user"
+	roleAdmin = "admin"
+)
+
+type contextKey string
+
+const userContextKey contextKey = "user"
+
 func (cfg *apiConfig) middlewareMetricsInc(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		_ = cfg.fileServerHits.Add(1)
@@ -28,3 +66,70 @@ func apiHandler(next http.HandlerFunc, prefix string) http.HandlerFunc {
 		api.ServeHTTP(w, r)
 	})
 }
+
+// requireRole authenticates the Bearer JWT, loads the user into the request
+// context and only lets them through if they hold one of roles. Without
+// roles any signed in user passes.
+func (cfg *apiConfig) requireRole(next http.HandlerFunc, roles ...string) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokenString, err := auth.GetBearerToken(r.Header)
+		if err != nil {
+			w.Header().Set("Content-Type", "plain/text")
+			w.WriteHeader(401)
+			w.Write([]byte("Error reading authorisation from header. " + err.Error()))
+			return
+		}
+
+		uid, err := auth.ValidateJWT(tokenString, cfg.secret)
+		if err != nil {
+			w.Header().Set("Content-Type", "plain/text")
+			w.WriteHeader(401)
+			w.Write([]byte("Error parsing user from JWT token. " + err.Error()))
+			return
+		}
+
+		user, err := cfg.db.GetUserByID(r.Context(), uid)
+		if err != nil {
+			w.Header().Set("Content-Type", "plain/text")
+			w.WriteHeader(401)
+			w.Write([]byte("Signed user no longer exists."))
+			return
+		}
+
+		if user.DeletionRequestedAt.Valid {
+			w.Header().Set("Content-Type", "plain/text")
+			w.WriteHeader(401)
+			w.Write([]byte("Account is pending deletion, log in again to cancel."))
+			return
+		}
+
+		if len(roles) > 0 && !slices.Contains(roles, user.Role) {
+			w.Header().Set("Content-Type", "plain/text")
+			w.WriteHeader(403)
+			w.Write([]byte("Role " + user.Role + " may not access this route."))
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userContextKey, user)))
+	})
+}
+
+// userFromContext returns the user stored by requireRole.
+func userFromContext(ctx context.Context) (database.User, bool) {
+	user, ok := ctx.Value(userContextKey).(database.User)
+	return user, ok
+}
+
+// viewerFromRequest returns who is asking on routes where signing in is
+// optional, or uuid.Nil when the request is anonymous or its token invalid.
+func (cfg *apiConfig) viewerFromRequest(req *http.Request) uuid.UUID {
+	tokenString, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		return uuid.Nil
+	}
+	uid, err := auth.ValidateJWT(tokenString, cfg.secret)
+	if err != nil {
+		return uuid.Nil
+	}
+	return uid
+}
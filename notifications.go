@@ -0,0 +1,344 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/anantashahane/Chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// Kinds of notification a user can get, stored in notifications.type. For
+// likes and rechirps the chirp is the recipient's own, for replies and
+// mentions it is the new chirp, so only the former group together.
+var notificationTypes = []string{"follow", "like", "mention", "rechirp", "reply"}
+
+type notificationResponseBody struct {
+	ID         string   `json:"id"`
+	Type       string   `json:"type"`
+	ChirpID    string   `json:"chirp_id,omitempty"`
+	ActorIDs   []string `json:"actor_ids"`
+	ActorCount int64    `json:"actor_count"`
+	Unread     bool     `json:"unread"`
+	CreatedAt  string   `json:"created_at"`
+}
+
+// notify records that actorID did something of the given type to
+// recipientID, in the caller's transaction. It does nothing when they are the
+// same user, when either has blocked the other, when the recipient turned the
+// type off or is deleting their account, or when the actor already notified
+// them of the same thing.
+func notify(ctx context.Context, q *database.Queries, recipientID, actorID uuid.UUID, notificationType string, chirpID uuid.NullUUID) error {
+	if recipientID == actorID {
+		return nil
+	}
+	notification := database.CreateNotificationParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UserID:    recipientID,
+		ActorID:   actorID,
+		Type:      notificationType,
+		ChirpID:   chirpID,
+	}
+	created, err := q.CreateNotification(ctx, notification)
+	if err != nil || created == 0 {
+		return err
+	}
+
+	// Live clients get it over the WebSocket.
+	streamed := struct {
+		ID        string `json:"id"`
+		Type      string `json:"type"`
+		ActorID   string `json:"actor_id"`
+		ChirpID   string `json:"chirp_id,omitempty"`
+		CreatedAt string `json:"created_at"`
+	}{
+		ID:        notification.ID.String(),
+		Type:      notificationType,
+		ActorID:   actorID.String(),
+		CreatedAt: notification.CreatedAt.String(),
+	}
+	if chirpID.Valid {
+		streamed.ChirpID = chirpID.UUID.String()
+	}
+	return recordStreamEvent(ctx, q, "notification.created", actorID, []string{notificationsTopic(recipientID)}, streamed)
+}
+
+// Users are mentioned as @<user ID>, since they have no handles. Past
+// maxMentions the rest of a chirp's mentions notify no one.
+var mentionPattern = regexp.MustCompile(`@([0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12})`)
+
+const maxMentions = 10
+
+// chirpMentions returns the distinct users mentioned in body.
+func chirpMentions(body string) []uuid.UUID {
+	mentioned := []uuid.UUID{}
+	for _, match := range mentionPattern.FindAllStringSubmatch(body, -1) {
+		userID, err := uuid.Parse(match[1])
+		if err != nil || slices.Contains(mentioned, userID) {
+			continue
+		}
+		mentioned = append(mentioned, userID)
+		if len(mentioned) == maxMentions {
+			break
+		}
+	}
+	return mentioned
+}
+
+// notifyMentions notifies the users chirp mentions that it was published,
+// through q. Followers-only chirps notify no one, since only their author can
+// read them.
+func notifyMentions(ctx context.Context, q *database.Queries, chirp database.Chirp) error {
+	if chirp.Visibility == visibilityFollowers {
+		return nil
+	}
+	for _, userID := range chirpMentions(chirp.Body) {
+		err := notify(ctx, q, userID, chirp.UserID, "mention", uuid.NullUUID{UUID: chirp.ID, Valid: true})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// notificationsTopic is the private stream of a user's new notifications.
+func notificationsTopic(userID uuid.UUID) string {
+	return "notifications:" + userID.String()
+}
+
+// listNotificationsHandler pages through the caller's notifications, newest
+// first, with everything of one type about the same chirp folded into a
+// single entry. Actors the caller blocked, was blocked by or muted are left
+// out.
+func (apiCfg *apiConfig) listNotificationsHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	type responseBody struct {
+		Notifications []notificationResponseBody `json:"notifications"`
+		UnreadCount   int64                      `json:"unread_count"`
+		NextCursor    string                     `json:"next_cursor,omitempty"`
+	}
+
+	userData, _ := userFromContext(req.Context())
+
+	cursor, err := parsePageCursor(req.URL.Query().Get("cursor"))
+	if err != nil {
+		responseWriter.WriteHeader(400)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte(err.Error()))
+		return
+	}
+	limit := pageLimit(req)
+
+	groups, err := apiCfg.db.GetNotificationGroups(context.Background(), database.GetNotificationGroupsParams{
+		UserID:     userData.ID,
+		Before:     cursor.Time,
+		BeforeID:   cursor.ID,
+		MaxResults: limit,
+	})
+	if err != nil {
+		responseWriter.WriteHeader(500)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Internal Server failed to access database."))
+		return
+	}
+	unread, err := apiCfg.db.CountUnreadNotificationGroups(context.Background(), userData.ID)
+	if err != nil {
+		responseWriter.WriteHeader(500)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Internal Server failed to access database."))
+		return
+	}
+
+	responseData := responseBody{Notifications: []notificationResponseBody{}, UnreadCount: unread}
+	for _, group := range groups {
+		notification := notificationResponseBody{
+			ID:         group.LatestID.String(),
+			Type:       group.Type,
+			ActorIDs:   []string{},
+			ActorCount: group.ActorCount,
+			Unread:     group.Unread,
+			CreatedAt:  group.LatestAt.String(),
+		}
+		if group.ChirpID.Valid {
+			notification.ChirpID = group.ChirpID.UUID.String()
+		}
+		for _, actorID := range group.RecentActorIds {
+			notification.ActorIDs = append(notification.ActorIDs, actorID.String())
+		}
+		responseData.Notifications = append(responseData.Notifications, notification)
+	}
+	if len(groups) == int(limit) {
+		last := groups[len(groups)-1]
+		responseData.NextCursor = pageCursor{Time: last.LatestAt, ID: last.LatestID}.String()
+	}
+
+	responseWriter.Header().Set("Content-Type", "application/json")
+	responseWriter.WriteHeader(200)
+	json.NewEncoder(responseWriter).Encode(responseData)
+}
+
+func (apiCfg *apiConfig) unreadNotificationsHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	type responseBody struct {
+		UnreadCount int64 `json:"unread_count"`
+	}
+
+	userData, _ := userFromContext(req.Context())
+
+	unread, err := apiCfg.db.CountUnreadNotificationGroups(context.Background(), userData.ID)
+	if err != nil {
+		responseWriter.WriteHeader(500)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Internal Server failed to access database."))
+		return
+	}
+
+	responseWriter.Header().Set("Content-Type", "application/json")
+	responseWriter.WriteHeader(200)
+	json.NewEncoder(responseWriter).Encode(responseBody{UnreadCount: unread})
+}
+
+// markNotificationReadHandler marks a listed notification read, along with
+// the older ones folded into it.
+func (apiCfg *apiConfig) markNotificationReadHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	userData, _ := userFromContext(req.Context())
+
+	notificationID, err := uuid.Parse(req.PathValue("notificationID"))
+	if err != nil {
+		responseWriter.WriteHeader(404)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Error parsing notification id."))
+		return
+	}
+
+	_, err = apiCfg.db.GetNotification(context.Background(), database.GetNotificationParams{
+		ID:     notificationID,
+		UserID: userData.ID,
+	})
+	if err != nil {
+		responseWriter.WriteHeader(404)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("No such notification " + notificationID.String()))
+		return
+	}
+
+	err = apiCfg.db.MarkNotificationGroupRead(context.Background(), database.MarkNotificationGroupReadParams{
+		UserID: userData.ID,
+		ID:     notificationID,
+		ReadAt: sql.NullTime{Time: time.Now(), Valid: true},
+	})
+	if err != nil {
+		responseWriter.WriteHeader(500)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Unable to mark notification read."))
+		return
+	}
+	responseWriter.WriteHeader(204)
+}
+
+func (apiCfg *apiConfig) markAllNotificationsReadHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	userData, _ := userFromContext(req.Context())
+
+	err := apiCfg.db.MarkAllNotificationsRead(context.Background(), database.MarkAllNotificationsReadParams{
+		UserID: userData.ID,
+		ReadAt: sql.NullTime{Time: time.Now(), Valid: true},
+	})
+	if err != nil {
+		responseWriter.WriteHeader(500)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Unable to mark notifications read."))
+		return
+	}
+	responseWriter.WriteHeader(204)
+}
+
+// notificationPreferences returns every notification type and whether the
+// user wants it. Types they never set are on.
+func (apiCfg *apiConfig) notificationPreferences(ctx context.Context, userID uuid.UUID) (map[string]bool, error) {
+	stored, err := apiCfg.db.GetNotificationPreferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	preferences := map[string]bool{}
+	for _, notificationType := range notificationTypes {
+		preferences[notificationType] = true
+	}
+	for _, preference := range stored {
+		preferences[preference.Type] = preference.Enabled
+	}
+	return preferences, nil
+}
+
+func (apiCfg *apiConfig) getNotificationPreferencesHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	userData, _ := userFromContext(req.Context())
+
+	preferences, err := apiCfg.notificationPreferences(context.Background(), userData.ID)
+	if err != nil {
+		responseWriter.WriteHeader(500)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Internal Server failed to access database."))
+		return
+	}
+
+	responseWriter.Header().Set("Content-Type", "application/json")
+	responseWriter.WriteHeader(200)
+	json.NewEncoder(responseWriter).Encode(preferences)
+}
+
+// updateNotificationPreferencesHandler turns the listed types on or off,
+// leaving the others as they were.
+func (apiCfg *apiConfig) updateNotificationPreferencesHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	userData, _ := userFromContext(req.Context())
+
+	requestData := map[string]bool{}
+	if err := json.NewDecoder(req.Body).Decode(&requestData); err != nil {
+		responseWriter.WriteHeader(400)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Json Decode failed."))
+		return
+	}
+	for notificationType := range requestData {
+		if !slices.Contains(notificationTypes, notificationType) {
+			responseWriter.WriteHeader(400)
+			responseWriter.Header().Set("Content-Type", "plain/text")
+			responseWriter.Write([]byte("Unknown notification type " + notificationType))
+			return
+		}
+	}
+
+	err := apiCfg.inTx(context.Background(), func(q *database.Queries) error {
+		for notificationType, enabled := range requestData {
+			err := q.SetNotificationPreference(context.Background(), database.SetNotificationPreferenceParams{
+				UserID:  userData.ID,
+				Type:    notificationType,
+				Enabled: enabled,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		responseWriter.WriteHeader(500)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Unable to save notification preferences."))
+		return
+	}
+
+	preferences, err := apiCfg.notificationPreferences(context.Background(), userData.ID)
+	if err != nil {
+		responseWriter.WriteHeader(500)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Internal Server failed to access database."))
+		return
+	}
+
+	responseWriter.Header().Set("Content-Type", "application/json")
+	responseWriter.WriteHeader(200)
+	json.NewEncoder(responseWriter).Encode(preferences)
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/anantashahane/Chirpy/internal/auth"
+	"github.com/anantashahane/Chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const oidcCookieName = "chirpy_oidc"
+
+// oidcLoginHandler starts the authorization code flow by redirecting to the
+// provider. State and nonce ride along in a short lived cookie.
+func (apiCfg *apiConfig) oidcLoginHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	if apiCfg.oidcProvider == nil {
+		responseWriter.WriteHeader(404)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("OpenID Connect sign in is not configured."))
+		return
+	}
+
+	state, err := auth.MakeRefreshedToken()
+	if err != nil {
+		responseWriter.WriteHeader(503)
+		return
+	}
+	nonce, err := auth.MakeRefreshedToken()
+	if err != nil {
+		responseWriter.WriteHeader(503)
+		return
+	}
+
+	http.SetCookie(responseWriter, &http.Cookie{
+		Name:     oidcCookieName,
+		Value:    state + "." + nonce,
+		Path:     "/api/oidc/",
+		MaxAge:   int((10 * time.Minute).Seconds()),
+		HttpOnly: true,
+		Secure:   apiCfg.platform != "dev",
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(responseWriter, req, apiCfg.oidcProvider.AuthCodeURL(state, nonce), http.StatusFound)
+}
+
+// oidcCallbackHandler finishes the flow: it exchanges the code, verifies the
+// ID token, links the external identity to a Chirpy user and signs them in.
+func (apiCfg *apiConfig) oidcCallbackHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	encoder := json.NewEncoder(responseWriter)
+	if apiCfg.oidcProvider == nil {
+		userErrorWriter(&responseWriter, encoder, "OpenID Connect sign in is not configured.", 404)
+		return
+	}
+
+	if providerError := req.URL.Query().Get("error"); providerError != "" {
+		userErrorWriter(&responseWriter, encoder, "Provider refused sign in: "+providerError, 401)
+		return
+	}
+
+	cookie, err := req.Cookie(oidcCookieName)
+	if err != nil {
+		userErrorWriter(&responseWriter, encoder, "Sign in session missing or expired, start again.", 401)
+		return
+	}
+	http.SetCookie(responseWriter, &http.Cookie{Name: oidcCookieName, Path: "/api/oidc/", MaxAge: -1})
+
+	state, nonce, found := strings.Cut(cookie.Value, ".")
+	if !found || subtle.ConstantTimeCompare([]byte(state), []byte(req.URL.Query().Get("state"))) != 1 {
+		userErrorWriter(&responseWriter, encoder, "State mismatch, start sign in again.", 401)
+		return
+	}
+
+	rawIDToken, err := apiCfg.oidcProvider.Exchange(req.Context(), req.URL.Query().Get("code"))
+	if err != nil {
+		userErrorWriter(&responseWriter, encoder, "Code exchange failed: "+err.Error(), 401)
+		return
+	}
+
+	claims, err := apiCfg.oidcProvider.VerifyIDToken(req.Context(), rawIDToken, nonce)
+	if err != nil {
+		userErrorWriter(&responseWriter, encoder, "ID token rejected: "+err.Error(), 401)
+		return
+	}
+
+	userData, err := apiCfg.userForIdentity(context.Background(), apiCfg.oidcProvider.Issuer, claims.Subject, claims.Email, claims.EmailVerified)
+	if err != nil {
+		userErrorWriter(&responseWriter, encoder, "Unable to link identity: "+err.Error(), 409)
+		return
+	}
+
+	token, refreshToken, err := apiCfg.issueTokens(userData.ID)
+	if err != nil {
+		userErrorWriter(&responseWriter, encoder, "Error generating tokens: "+err.Error(), 503)
+		return
+	}
+
+	responseData := userDataResponse{
+		ID:           userData.ID.String(),
+		CreatedAt:    userData.CreatedAt.String(),
+		UpdatedAt:    userData.UpdatedAt.String(),
+		Email:        userData.Email,
+		Token:        token,
+		RefreshToken: refreshToken,
+		IsRed:        userData.IsChirpyRed.Bool,
+	}
+	responseWriter.WriteHeader(200)
+	encoder.Encode(responseData)
+}
+
+// userForIdentity returns the Chirpy user linked to issuer+subject. Unknown
+// identities are linked to the account with the same email when the provider
+// vouches for it, otherwise a new password-less account is created.
+func (apiCfg *apiConfig) userForIdentity(ctx context.Context, issuer, subject, email string, emailVerified bool) (database.User, error) {
+	identity, err := apiCfg.db.GetUserIdentity(ctx, database.GetUserIdentityParams{
+		Issuer:  issuer,
+		Subject: subject,
+	})
+	if err == nil {
+		return apiCfg.db.GetUserByID(ctx, identity.UserID)
+	}
+
+	if email == "" {
+		return database.User{}, fmt.Errorf("provider did not share an email address")
+	}
+
+	userData, err := apiCfg.db.GetUser(ctx, email)
+	if err == nil && !emailVerified {
+		return database.User{}, fmt.Errorf("account %v exists but the provider has not verified that email", email)
+	}
+	if err != nil {
+		// Empty password hash, the account can only sign in through the provider.
+		userData, err = apiCfg.db.CreateUser(ctx, database.CreateUserParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+			Email:     email,
+			Password:  "",
+		})
+		if err != nil {
+			return database.User{}, err
+		}
+	}
+
+	_, err = apiCfg.db.CreateUserIdentity(ctx, database.CreateUserIdentityParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		UserID:    userData.ID,
+		Issuer:    issuer,
+		Subject:   subject,
+		Email:     email,
+	})
+	if err != nil {
+		return database.User{}, err
+	}
+	return userData, nil
+}
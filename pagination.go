@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// pageCursor marks where a page of a newest first listing ended: the sort
+// time and ID of its last row. Clients get it as an opaque string.
+type pageCursor struct {
+	Time time.Time
+	ID   uuid.UUID
+}
+
+// firstPage sorts after every row, so the first page starts at the newest.
+var firstPage = pageCursor{Time: time.Date(9999, 1, 1, 0, 0, 0, 0, time.UTC), ID: uuid.Max}
+
+func (cursor pageCursor) String() string {
+	raw := strconv.FormatInt(cursor.Time.UnixNano(), 10) + ":" + cursor.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func parsePageCursor(encoded string) (pageCursor, error) {
+	if encoded == "" {
+		return firstPage, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return pageCursor{}, fmt.Errorf("Invalid cursor.")
+	}
+	nanos, id, found := strings.Cut(string(raw), ":")
+	if !found {
+		return pageCursor{}, fmt.Errorf("Invalid cursor.")
+	}
+	unixNano, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return pageCursor{}, fmt.Errorf("Invalid cursor.")
+	}
+	cursorID, err := uuid.Parse(id)
+	if err != nil {
+		return pageCursor{}, fmt.Errorf("Invalid cursor.")
+	}
+	return pageCursor{Time: time.Unix(0, unixNano).UTC(), ID: cursorID}, nil
+}
+
+// pageLimit reads ?limit=, defaulting to 20 and capped at 100.
+func pageLimit(req *http.Request) int32 {
+	limit, err := strconv.Atoi(req.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		return 20
+	}
+	return int32(min(limit, 100))
+}
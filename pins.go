@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/anantashahane/Chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// How many chirps a user can pin to their profile.
+const maxPinnedChirps = 3
+
+var errTooManyPins = fmt.Errorf("You can pin at most %d chirps.", maxPinnedChirps)
+
+func (apiCfg *apiConfig) pinChirpHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	userData, _ := userFromContext(req.Context())
+
+	chirpID, err := uuid.Parse(req.PathValue("chirpID"))
+	if err != nil {
+		responseWriter.WriteHeader(404)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Error parsing chirp id."))
+		return
+	}
+
+	chirp, err := apiCfg.db.GetChirpByID(context.Background(), database.GetChirpByIDParams{
+		ID:       chirpID,
+		ViewerID: userData.ID,
+	})
+	if err != nil {
+		responseWriter.WriteHeader(404)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Chirp with ID " + chirpID.String() + " not found"))
+		return
+	}
+	if chirp.UserID != userData.ID {
+		responseWriter.WriteHeader(403)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("You can only pin your own chirps."))
+		return
+	}
+	if !chirp.Published {
+		responseWriter.WriteHeader(409)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Scheduled chirps can be pinned once they are published."))
+		return
+	}
+
+	if !chirp.PinnedPosition.Valid {
+		err = apiCfg.inTx(context.Background(), func(q *database.Queries) error {
+			pinned, err := q.CountPinnedChirps(context.Background(), userData.ID)
+			if err != nil {
+				return err
+			}
+			if pinned >= maxPinnedChirps {
+				return errTooManyPins
+			}
+			chirp, err = q.PinChirp(context.Background(), database.PinChirpParams{
+				ID:     chirp.ID,
+				UserID: userData.ID,
+			})
+			return err
+		})
+	}
+	if err != nil {
+		// Losing a race with a concurrent pin trips the unique position index.
+		responseWriter.WriteHeader(409)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		if errors.Is(err, errTooManyPins) {
+			responseWriter.Write([]byte(err.Error()))
+		} else {
+			responseWriter.Write([]byte("Unable to pin chirp, try again."))
+		}
+		return
+	}
+
+	responseWriter.Header().Set("Content-Type", "application/json")
+	responseWriter.WriteHeader(200)
+	json.NewEncoder(responseWriter).Encode(chirpResponse(chirp))
+}
+
+func (apiCfg *apiConfig) unpinChirpHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	userData, _ := userFromContext(req.Context())
+
+	chirpID, err := uuid.Parse(req.PathValue("chirpID"))
+	if err != nil {
+		responseWriter.WriteHeader(404)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Error parsing chirp id."))
+		return
+	}
+
+	_, err = apiCfg.db.UnpinChirp(context.Background(), database.UnpinChirpParams{
+		ID:     chirpID,
+		UserID: userData.ID,
+	})
+	if err != nil {
+		responseWriter.WriteHeader(404)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("You have no pinned chirp with ID " + chirpID.String()))
+		return
+	}
+	responseWriter.WriteHeader(204)
+}
+
+// reorderPinsHandler replaces the caller's pins with the given chirps, in
+// the given order.
+func (apiCfg *apiConfig) reorderPinsHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	type requestBody struct {
+		ChirpIDs []uuid.UUID `json:"chirp_ids"`
+	}
+
+	userData, _ := userFromContext(req.Context())
+
+	decoder := json.NewDecoder(req.Body)
+	decoder.DisallowUnknownFields()
+	requestData := requestBody{}
+	if err := decoder.Decode(&requestData); err != nil {
+		responseWriter.WriteHeader(400)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Json Decode failed."))
+		return
+	}
+	if len(requestData.ChirpIDs) > maxPinnedChirps {
+		responseWriter.WriteHeader(400)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte(errTooManyPins.Error()))
+		return
+	}
+	unique := slices.Clone(requestData.ChirpIDs)
+	slices.SortFunc(unique, func(a, b uuid.UUID) int { return slices.Compare(a[:], b[:]) })
+	if len(slices.Compact(unique)) != len(requestData.ChirpIDs) {
+		responseWriter.WriteHeader(400)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("A chirp can only be pinned once."))
+		return
+	}
+
+	pinned := []database.Chirp{}
+	err := apiCfg.inTx(context.Background(), func(q *database.Queries) error {
+		if err := q.ClearPinnedChirps(context.Background(), userData.ID); err != nil {
+			return err
+		}
+		for i, chirpID := range requestData.ChirpIDs {
+			chirp, err := q.SetChirpPinPosition(context.Background(), database.SetChirpPinPositionParams{
+				ID:             chirpID,
+				UserID:         userData.ID,
+				PinnedPosition: sql.NullInt32{Int32: int32(i + 1), Valid: true},
+			})
+			if errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("%s is not one of your published chirps: %w", chirpID, err)
+			}
+			if err != nil {
+				return err
+			}
+			pinned = append(pinned, chirp)
+		}
+		return nil
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		responseWriter.WriteHeader(404)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte(err.Error()))
+		return
+	}
+	if err != nil {
+		responseWriter.WriteHeader(409)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Unable to reorder pins, try again."))
+		return
+	}
+
+	responseBody := []chirpResponseBody{}
+	for _, chirp := range pinned {
+		responseBody = append(responseBody, chirpResponse(chirp))
+	}
+	responseWriter.Header().Set("Content-Type", "application/json")
+	responseWriter.WriteHeader(200)
+	json.NewEncoder(responseWriter).Encode(responseBody)
+}
+
+// userChirpsHandler lists a user's profile: pinned chirps in pin order, then
+// the rest newest first.
+func (apiCfg *apiConfig) userChirpsHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	userID, err := uuid.Parse(req.PathValue("userID"))
+	if err != nil {
+		responseWriter.WriteHeader(404)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Error parsing user id."))
+		return
+	}
+
+	viewerID := apiCfg.viewerFromRequest(req)
+	blocked, err := apiCfg.db.IsBlockedEitherWay(context.Background(), database.IsBlockedEitherWayParams{
+		UserID:  viewerID,
+		OtherID: userID,
+	})
+	if err != nil {
+		responseWriter.WriteHeader(500)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Internal Server failed to access database."))
+		return
+	}
+
+	// A blocked profile looks the same as a missing one, either way round.
+	author, err := apiCfg.db.GetUserByID(context.Background(), userID)
+	if err != nil || author.DeletionRequestedAt.Valid || blocked {
+		responseWriter.WriteHeader(404)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("No such user " + userID.String()))
+		return
+	}
+
+	includeSensitive, status, err := apiCfg.includeSensitive(req, viewerID)
+	if err != nil {
+		responseWriter.WriteHeader(status)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte(err.Error()))
+		return
+	}
+
+	chirps, err := apiCfg.db.GetUserChirps(context.Background(), database.GetUserChirpsParams{
+		UserID:           author.ID,
+		ViewerID:         viewerID,
+		IncludeSensitive: includeSensitive,
+	})
+	if err != nil {
+		responseWriter.WriteHeader(500)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Internal Server failed to access database."))
+		return
+	}
+
+	responseBody := []chirpResponseBody{}
+	for _, chirp := range chirps {
+		responseBody = append(responseBody, chirpResponse(chirp))
+	}
+	responseWriter.Header().Set("Content-Type", "application/json")
+	responseWriter.WriteHeader(200)
+	json.NewEncoder(responseWriter).Encode(responseBody)
+}
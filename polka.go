@@ -0,0 +1,317 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/anantashahane/Chirpy/internal/auth"
+	"github.com/anantashahane/Chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// Statuses stored in webhook_events.status.
+const (
+	webhookProcessed = "processed"
+	webhookFailed    = "failed"
+	webhookIgnored   = "ignored"
+)
+
+// polkaClaimLease is how long an event may sit in processing before it is
+// assumed abandoned, e.g. by a crash, and can be claimed again.
+const polkaClaimLease = 5 * time.Minute
+
+var (
+	errUnknownPolkaEvent = errors.New("unknown event")
+	errStalePolkaEvent   = errors.New("event is older than the subscription")
+)
+
+type polkaEvent struct {
+	ID    string `json:"id"`
+	Event string `json:"event"`
+	Data  struct {
+		UserID           string     `json:"user_id"`
+		Plan             string     `json:"plan"`
+		CurrentPeriodEnd *time.Time `json:"current_period_end"`
+	} `json:"data"`
+}
+
+type webhookEventResponseBody struct {
+	ID        string `json:"id"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+	Event     string `json:"event"`
+	Payload   string `json:"payload"`
+	Status    string `json:"status"`
+	Error     string `json:"error,omitempty"`
+	Attempts  int32  `json:"attempts"`
+}
+
+func webhookEventResponse(event database.WebhookEvent) webhookEventResponseBody {
+	return webhookEventResponseBody{
+		ID:        event.ID,
+		CreatedAt: event.CreatedAt.String(),
+		UpdatedAt: event.UpdatedAt.String(),
+		Event:     event.Event,
+		Payload:   event.Payload,
+		Status:    event.Status,
+		Error:     event.Error.String,
+		Attempts:  event.Attempts,
+	}
+}
+
+// authenticatePolka checks the webhook came from Polka. With signing secrets
+// configured the HMAC signature is required, otherwise the ApiKey is checked.
+func (apiCfg *apiConfig) authenticatePolka(headers http.Header, body []byte) error {
+	if len(apiCfg.polkaSecrets) > 0 {
+		timestamp, signatures, err := auth.GetPolkaSignature(headers)
+		if err != nil {
+			return err
+		}
+		return auth.VerifySignature(timestamp, signatures, body, apiCfg.polkaSecrets, apiCfg.polkaTolerance, time.Now())
+	}
+
+	polkaKey, err := auth.GetPolkaKey(headers)
+	if err != nil {
+		return err
+	}
+	if apiCfg.polkaKey == "" || subtle.ConstantTimeCompare([]byte(polkaKey), []byte(apiCfg.polkaKey)) != 1 {
+		return fmt.Errorf("Incorrect Polka key.")
+	}
+	return nil
+}
+
+// polkaEventID identifies a delivery for deduplication: the X-Polka-Event-Id
+// header, else the payload's id, else a hash of X-Polka-Timestamp and the
+// body. Polka signs that same pair, so a retry of a signed delivery keeps its
+// ID while a new event with the same body gets another one. Without signing
+// there is no timestamp and identical bodies collapse into one event.
+func polkaEventID(headers http.Header, event polkaEvent, body []byte) string {
+	if id := headers.Get("X-Polka-Event-Id"); id != "" {
+		return id
+	}
+	if event.ID != "" {
+		return event.ID
+	}
+	sum := sha256.Sum256([]byte(headers.Get("X-Polka-Timestamp") + "." + string(body)))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// polkaSentAt is when Polka sent a delivery, from its signed timestamp.
+// Without signing the timestamp can't be trusted and the time is unknown.
+func (apiCfg *apiConfig) polkaSentAt(headers http.Header) sql.NullTime {
+	if len(apiCfg.polkaSecrets) == 0 {
+		return sql.NullTime{}
+	}
+	seconds, err := strconv.ParseInt(headers.Get("X-Polka-Timestamp"), 10, 64)
+	if err != nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: time.Unix(seconds, 0), Valid: true}
+}
+
+func (apiCfg *apiConfig) upgradeUserHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+	body, err := io.ReadAll(http.MaxBytesReader(responseWriter, req.Body, 1<<20))
+	if err != nil {
+		fmt.Println("Unable to read webhook body.")
+		responseWriter.WriteHeader(400)
+		return
+	}
+
+	if err := apiCfg.authenticatePolka(req.Header, body); err != nil {
+		fmt.Println("Rejected Polka webhook: " + err.Error())
+		responseWriter.WriteHeader(401)
+		return
+	}
+
+	// Undecodable payloads are still logged so they can be inspected.
+	eventData := polkaEvent{}
+	decodeErr := json.Unmarshal(body, &eventData)
+
+	eventID := polkaEventID(req.Header, eventData, body)
+	_, err = apiCfg.db.CreateWebhookEvent(context.Background(), database.CreateWebhookEventParams{
+		ID:        eventID,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		Event:     eventData.Event,
+		Payload:   string(body),
+		SentAt:    apiCfg.polkaSentAt(req.Header),
+	})
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		fmt.Println("Unable to log webhook event: " + err.Error())
+		responseWriter.WriteHeader(500)
+		return
+	}
+
+	if decodeErr != nil {
+		apiCfg.finishWebhookEvent(eventID, webhookFailed, decodeErr)
+		fmt.Println("Unable to decode data.")
+		responseWriter.WriteHeader(404)
+		return
+	}
+
+	// Only pending, failed or abandoned events are claimed, so a redelivery of
+	// an event that was already handled (or is being handled) is acknowledged
+	// as is.
+	claimed, err := apiCfg.db.ClaimWebhookEvent(context.Background(), database.ClaimWebhookEventParams{
+		UpdatedAt:   time.Now(),
+		ID:          eventID,
+		StaleBefore: time.Now().Add(-polkaClaimLease),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		responseWriter.WriteHeader(204)
+		return
+	}
+	if err != nil {
+		fmt.Println("Unable to claim webhook event: " + err.Error())
+		responseWriter.WriteHeader(500)
+		return
+	}
+
+	err = apiCfg.processPolkaEvent(context.Background(), eventData, claimed.SentAt)
+	switch {
+	case errors.Is(err, errUnknownPolkaEvent):
+		apiCfg.finishWebhookEvent(eventID, webhookIgnored, nil)
+		fmt.Println("Unknown Event " + eventData.Event)
+		responseWriter.WriteHeader(204)
+	case errors.Is(err, errStalePolkaEvent):
+		apiCfg.finishWebhookEvent(eventID, webhookIgnored, err)
+		fmt.Println("Stale Event " + eventID)
+		responseWriter.WriteHeader(204)
+	case err != nil:
+		apiCfg.finishWebhookEvent(eventID, webhookFailed, err)
+		fmt.Println(err.Error())
+		responseWriter.WriteHeader(404)
+	default:
+		apiCfg.finishWebhookEvent(eventID, webhookProcessed, nil)
+		responseWriter.WriteHeader(204)
+	}
+}
+
+// processPolkaEvent applies a single event sent at sentAt. It must be safe to
+// run again on replay.
+func (apiCfg *apiConfig) processPolkaEvent(ctx context.Context, eventData polkaEvent, sentAt sql.NullTime) error {
+	switch eventData.Event {
+	case "user.upgraded", "user.downgraded", "subscription.renewed", "subscription.canceled", "payment.failed":
+	default:
+		return errUnknownPolkaEvent
+	}
+
+	uid, err := uuid.Parse(eventData.Data.UserID)
+	if err != nil {
+		return fmt.Errorf("Unable to parse data.")
+	}
+	userInDB, err := apiCfg.db.GetUserByID(ctx, uid)
+	if err != nil {
+		return fmt.Errorf("User not in DB.")
+	}
+	err = apiCfg.applySubscriptionEvent(ctx, userInDB.ID, eventData, sentAt)
+	if err != nil {
+		return fmt.Errorf("Unable to update subscription in DB: %w", err)
+	}
+	return nil
+}
+
+func (apiCfg *apiConfig) finishWebhookEvent(eventID, status string, cause error) database.WebhookEvent {
+	event, err := apiCfg.db.FinishWebhookEvent(context.Background(), database.FinishWebhookEventParams{
+		Status:    status,
+		Error:     sql.NullString{String: fmt.Sprint(cause), Valid: cause != nil},
+		UpdatedAt: time.Now(),
+		ID:        eventID,
+	})
+	if err != nil {
+		fmt.Println("Unable to record webhook event " + eventID + ": " + err.Error())
+	}
+	return event
+}
+
+func (apiCfg *apiConfig) listWebhookEventsHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	limit, err := strconv.Atoi(req.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 || limit > 500 {
+		limit = 100
+	}
+
+	events, err := apiCfg.db.ListWebhookEvents(context.Background(), database.ListWebhookEventsParams{
+		Status:     req.URL.Query().Get("status"),
+		MaxResults: int32(limit),
+	})
+	if err != nil {
+		responseWriter.WriteHeader(500)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Internal Server failed to access database."))
+		return
+	}
+
+	responseBody := []webhookEventResponseBody{}
+	for _, event := range events {
+		responseBody = append(responseBody, webhookEventResponse(event))
+	}
+	responseWriter.Header().Set("Content-Type", "application/json")
+	responseWriter.WriteHeader(200)
+	json.NewEncoder(responseWriter).Encode(responseBody)
+}
+
+func (apiCfg *apiConfig) replayWebhookEventHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	eventID := req.PathValue("eventID")
+
+	event, err := apiCfg.db.GetWebhookEvent(context.Background(), eventID)
+	if err != nil {
+		responseWriter.WriteHeader(404)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("No such webhook event " + eventID))
+		return
+	}
+	abandoned := event.Status == "processing" && event.UpdatedAt.Before(time.Now().Add(-polkaClaimLease))
+	if event.Status != webhookFailed && !abandoned {
+		responseWriter.WriteHeader(409)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Only failed or abandoned events can be replayed, this one is " + event.Status))
+		return
+	}
+
+	eventData := polkaEvent{}
+	if err := json.Unmarshal([]byte(event.Payload), &eventData); err != nil {
+		responseWriter.WriteHeader(422)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Stored payload is not valid JSON."))
+		return
+	}
+
+	_, err = apiCfg.db.ClaimWebhookEvent(context.Background(), database.ClaimWebhookEventParams{
+		UpdatedAt:   time.Now(),
+		ID:          eventID,
+		StaleBefore: time.Now().Add(-polkaClaimLease),
+	})
+	if err != nil {
+		responseWriter.WriteHeader(409)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Event is already being processed."))
+		return
+	}
+
+	err = apiCfg.processPolkaEvent(context.Background(), eventData, event.SentAt)
+	switch {
+	case errors.Is(err, errUnknownPolkaEvent):
+		event = apiCfg.finishWebhookEvent(eventID, webhookIgnored, nil)
+	case errors.Is(err, errStalePolkaEvent):
+		event = apiCfg.finishWebhookEvent(eventID, webhookIgnored, err)
+	case err != nil:
+		event = apiCfg.finishWebhookEvent(eventID, webhookFailed, err)
+	default:
+		event = apiCfg.finishWebhookEvent(eventID, webhookProcessed, nil)
+	}
+
+	responseWriter.Header().Set("Content-Type", "application/json")
+	responseWriter.WriteHeader(200)
+	json.NewEncoder(responseWriter).Encode(webhookEventResponse(event))
+}
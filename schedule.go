@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/anantashahane/Chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+type publishChirpJob struct {
+	ChirpID uuid.UUID `json:"chirp_id"`
+}
+
+// publishScheduledChirp runs at a scheduled chirp's publish_at. Publishing
+// only flips unpublished chirps, in the same transaction as the chirp.created
+// event, so a job that runs twice after a restart publishes once, and a job
+// for a cancelled chirp does nothing.
+func (cfg *apiConfig) publishScheduledChirp(ctx context.Context, job publishChirpJob) error {
+	return cfg.inTx(ctx, func(q *database.Queries) error {
+		chirp, err := q.PublishChirp(ctx, database.PublishChirpParams{
+			ID:        job.ChirpID,
+			UpdatedAt: time.Now(),
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := emitEvent(ctx, q, "chirp.created", chirp, chirpResponse(chirp)); err != nil {
+			return err
+		}
+		if err := publishStreamEvent(ctx, q, "chirp.created", chirp, chirpResponse(chirp)); err != nil {
+			return err
+		}
+		return notifyMentions(ctx, q, chirp)
+	})
+}
+
+func (apiCfg *apiConfig) getScheduledChirpsHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	userData, _ := userFromContext(req.Context())
+
+	chirps, err := apiCfg.db.GetScheduledChirps(context.Background(), userData.ID)
+	if err != nil {
+		responseWriter.WriteHeader(500)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Internal Server failed to access database."))
+		return
+	}
+
+	responseBody := []chirpResponseBody{}
+	for _, chirp := range chirps {
+		responseBody = append(responseBody, chirpResponse(chirp))
+	}
+	responseWriter.Header().Set("Content-Type", "application/json")
+	responseWriter.WriteHeader(200)
+	json.NewEncoder(responseWriter).Encode(responseBody)
+}
+
+func (apiCfg *apiConfig) cancelScheduledChirpHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	userData, _ := userFromContext(req.Context())
+
+	chirpID, err := uuid.Parse(req.PathValue("chirpID"))
+	if err != nil {
+		responseWriter.WriteHeader(404)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Error parsing chirp id."))
+		return
+	}
+
+	_, err = apiCfg.db.CancelScheduledChirp(context.Background(), database.CancelScheduledChirpParams{
+		ID:     chirpID,
+		UserID: userData.ID,
+	})
+	if err != nil {
+		responseWriter.WriteHeader(404)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("No scheduled chirp with ID " + chirpID.String()))
+		return
+	}
+	responseWriter.WriteHeader(204)
+}
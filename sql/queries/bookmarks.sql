@@ -0,0 +1,29 @@
+-- name: CreateBookmark :exec
+INSERT INTO bookmarks (user_id, chirp_id, created_at)
+VALUES (
+    $1,
+    $2,
+    $3
+) ON CONFLICT DO NOTHING;
+
+-- name: DeleteBookmark :exec
+DELETE FROM bookmarks
+WHERE user_id = $1 AND chirp_id = $2;
+
+-- name: GetBookmarks :many
+SELECT sqlc.embed(chirps), bookmarks.created_at AS bookmarked_at
+FROM bookmarks
+JOIN chirps ON chirps.id = bookmarks.chirp_id
+JOIN users ON users.id = chirps.user_id
+WHERE bookmarks.user_id = sqlc.arg(user_id)
+AND chirps.published AND chirps.deleted_at IS NULL AND users.deletion_requested_at IS NULL
+AND (chirps.visibility <> 'followers' OR chirps.user_id = sqlc.arg(user_id))
+AND NOT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocks.blocker_id = sqlc.arg(user_id) AND blocks.blocked_id = chirps.user_id)
+    OR (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = sqlc.arg(user_id))
+)
+AND (bookmarks.created_at, bookmarks.chirp_id) < (sqlc.arg(before)::timestamp, sqlc.arg(before_id)::uuid)
+AND (sqlc.arg(include_sensitive)::boolean OR NOT (chirps.sensitive OR chirps.content_warning <> '') OR chirps.user_id = sqlc.arg(user_id))
+ORDER BY bookmarks.created_at DESC, bookmarks.chirp_id DESC
+LIMIT sqlc.arg(max_results);
@@ -1,22 +1,173 @@
 -- name: CreateChirps :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, published, publish_at, visibility, content_warning, sensitive)
 VALUES (
     $1,
     $2,
     $3,
     $4,
-    $5
+    $5,
+    $6,
+    $7,
+    $8,
+    $9,
+    $10
 ) RETURNING *;
 
 -- name: GetChirps :many
+SELECT chirps.* FROM chirps
+JOIN users ON users.id = chirps.user_id
+WHERE users.deletion_requested_at IS NULL AND chirps.published AND chirps.deleted_at IS NULL
+AND (chirps.visibility = 'public' OR chirps.user_id = sqlc.arg(viewer_id))
+AND (sqlc.arg(include_sensitive)::boolean OR NOT (chirps.sensitive OR chirps.content_warning <> '') OR chirps.user_id = sqlc.arg(viewer_id))
+AND NOT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocks.blocker_id = sqlc.arg(viewer_id) AND blocks.blocked_id = chirps.user_id)
+    OR (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = sqlc.arg(viewer_id))
+)
+AND NOT EXISTS (
+    SELECT 1 FROM mutes
+    WHERE mutes.muter_id = sqlc.arg(viewer_id) AND mutes.muted_id = chirps.user_id
+)
+ORDER BY chirps.created_at;
+
+-- name: GetChirpByID :one
+SELECT chirps.* FROM chirps
+JOIN users ON users.id = chirps.user_id
+WHERE chirps.id = sqlc.arg(id) AND users.deletion_requested_at IS NULL AND chirps.deleted_at IS NULL
+AND (chirps.published OR chirps.user_id = sqlc.arg(viewer_id))
+AND (chirps.visibility <> 'followers' OR chirps.user_id = sqlc.arg(viewer_id))
+AND NOT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocks.blocker_id = sqlc.arg(viewer_id) AND blocks.blocked_id = chirps.user_id)
+    OR (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = sqlc.arg(viewer_id))
+);
+
+-- name: SoftDeleteChirp :one
+UPDATE chirps
+SET deleted_at = $2, pinned_position = NULL
+WHERE id = $1 AND deleted_at IS NULL
+RETURNING *;
+
+-- name: RestoreChirp :one
+UPDATE chirps
+SET deleted_at = NULL, updated_at = $3
+WHERE id = $1 AND user_id = $2 AND deleted_at > $4
+RETURNING *;
+
+-- name: GetTrashedChirps :many
 SELECT * FROM chirps
+WHERE user_id = $1 AND deleted_at > $2
+ORDER BY deleted_at DESC;
+
+-- name: GetChirpTombstone :one
+SELECT id, deleted_at FROM chirps
+WHERE id = sqlc.arg(id) AND deleted_at IS NOT NULL AND published
+AND (chirps.visibility <> 'followers' OR chirps.user_id = sqlc.arg(viewer_id))
+AND NOT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocks.blocker_id = sqlc.arg(viewer_id) AND blocks.blocked_id = chirps.user_id)
+    OR (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = sqlc.arg(viewer_id))
+);
+
+-- name: PurgeTrashedChirps :exec
+DELETE FROM chirps
+WHERE deleted_at < $1;
+
+-- name: GetChirpsByAuthor :many
+SELECT * FROM chirps
+WHERE user_id = $1
 ORDER BY created_at;
 
--- name: GetChirpByID :one
+-- name: UpdateChirpContent :one
+UPDATE chirps
+SET body = $1, content_warning = $2, sensitive = $3, updated_at = $4
+WHERE id = $5
+RETURNING *;
+
+-- name: GetChirpStats :one
+SELECT
+    COUNT(*) AS total_chirps,
+    COUNT(*) FILTER (WHERE created_at > sqlc.arg(week_ago)) AS chirps_last_7_days,
+    COUNT(*) FILTER (WHERE created_at > sqlc.arg(month_ago)) AS chirps_last_30_days,
+    COALESCE(AVG(LENGTH(body)), 0)::float8 AS average_length,
+    COALESCE(MAX(LENGTH(body)), 0)::int AS longest_chirp
+FROM chirps
+WHERE user_id = sqlc.arg(user_id) AND published AND deleted_at IS NULL;
+
+-- name: GetScheduledChirps :many
 SELECT * FROM chirps
-WHERE id = $1;
+WHERE user_id = $1 AND NOT published AND deleted_at IS NULL
+ORDER BY publish_at;
+
+-- name: PublishChirp :one
+UPDATE chirps
+SET published = TRUE, updated_at = $2
+WHERE id = $1 AND NOT published AND deleted_at IS NULL
+RETURNING *;
 
--- name: DeleteChirpByID :one
+-- name: CancelScheduledChirp :one
 DELETE FROM chirps
-WHERE id = $1
+WHERE id = $1 AND user_id = $2 AND NOT published
+RETURNING *;
+
+-- name: PinChirp :one
+UPDATE chirps
+SET pinned_position = (
+    SELECT COALESCE(MAX(pinned.pinned_position), 0) + 1 FROM chirps AS pinned
+    WHERE pinned.user_id = $2
+)
+WHERE chirps.id = $1 AND chirps.user_id = $2 AND chirps.published AND chirps.deleted_at IS NULL
+AND chirps.pinned_position IS NULL
+RETURNING *;
+
+-- name: UnpinChirp :one
+UPDATE chirps
+SET pinned_position = NULL
+WHERE id = $1 AND user_id = $2 AND pinned_position IS NOT NULL
+RETURNING *;
+
+-- name: ClearPinnedChirps :exec
+UPDATE chirps
+SET pinned_position = NULL
+WHERE user_id = $1 AND pinned_position IS NOT NULL;
+
+-- name: SetChirpPinPosition :one
+UPDATE chirps
+SET pinned_position = $3
+WHERE id = $1 AND user_id = $2 AND published AND deleted_at IS NULL
 RETURNING *;
+
+-- name: CountPinnedChirps :one
+SELECT COUNT(*) FROM chirps
+WHERE user_id = $1 AND pinned_position IS NOT NULL;
+
+-- name: GetUserChirps :many
+SELECT chirps.* FROM chirps
+JOIN users ON users.id = chirps.user_id
+WHERE chirps.user_id = sqlc.arg(user_id) AND users.deletion_requested_at IS NULL
+AND chirps.published AND chirps.deleted_at IS NULL
+AND (chirps.visibility = 'public' OR chirps.user_id = sqlc.arg(viewer_id))
+AND (sqlc.arg(include_sensitive)::boolean OR NOT (chirps.sensitive OR chirps.content_warning <> '') OR chirps.user_id = sqlc.arg(viewer_id))
+AND NOT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocks.blocker_id = sqlc.arg(viewer_id) AND blocks.blocked_id = chirps.user_id)
+    OR (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = sqlc.arg(viewer_id))
+)
+ORDER BY chirps.pinned_position ASC NULLS LAST, chirps.created_at DESC;
+
+-- name: GetListChirps :many
+SELECT chirps.* FROM chirps
+JOIN list_members ON list_members.user_id = chirps.user_id
+JOIN users ON users.id = chirps.user_id
+WHERE list_members.list_id = sqlc.arg(list_id) AND users.deletion_requested_at IS NULL
+AND chirps.published AND chirps.deleted_at IS NULL
+AND (chirps.visibility = 'public' OR chirps.user_id = sqlc.arg(viewer_id))
+AND (sqlc.arg(include_sensitive)::boolean OR NOT (chirps.sensitive OR chirps.content_warning <> '') OR chirps.user_id = sqlc.arg(viewer_id))
+AND NOT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocks.blocker_id = sqlc.arg(viewer_id) AND blocks.blocked_id = chirps.user_id)
+    OR (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = sqlc.arg(viewer_id))
+)
+AND (chirps.created_at, chirps.id) < (sqlc.arg(before)::timestamp, sqlc.arg(before_id)::uuid)
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg(max_results);
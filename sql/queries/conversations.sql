@@ -0,0 +1,96 @@
+-- name: CreateConversation :one
+INSERT INTO conversations (id, created_at, updated_at, created_by)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4
+) RETURNING *;
+
+-- name: AddConversationMember :exec
+INSERT INTO conversation_members (conversation_id, user_id, joined_at)
+VALUES (
+    $1,
+    $2,
+    $3
+);
+
+-- name: LockDirectConversation :exec
+SELECT pg_advisory_xact_lock(hashtext('conversations'), hashtext(LEAST(sqlc.arg(user_id)::uuid, sqlc.arg(other_id)::uuid)::text || GREATEST(sqlc.arg(user_id)::uuid, sqlc.arg(other_id)::uuid)::text));
+
+-- name: FindDirectConversation :one
+SELECT conversations.* FROM conversations
+WHERE (SELECT COUNT(*) FROM conversation_members WHERE conversation_members.conversation_id = conversations.id) = 2
+AND EXISTS (
+    SELECT 1 FROM conversation_members
+    WHERE conversation_members.conversation_id = conversations.id AND conversation_members.user_id = sqlc.arg(user_id)
+)
+AND EXISTS (
+    SELECT 1 FROM conversation_members
+    WHERE conversation_members.conversation_id = conversations.id AND conversation_members.user_id = sqlc.arg(other_id)
+)
+LIMIT 1;
+
+-- name: GetConversationForMember :one
+SELECT conversations.* FROM conversations
+JOIN conversation_members ON conversation_members.conversation_id = conversations.id
+WHERE conversations.id = sqlc.arg(id) AND conversation_members.user_id = sqlc.arg(user_id);
+
+-- name: GetConversationMembers :many
+SELECT * FROM conversation_members
+WHERE conversation_id = $1
+ORDER BY joined_at, user_id;
+
+-- name: IsBlockedInConversation :one
+SELECT EXISTS (
+    SELECT 1 FROM conversation_members
+    JOIN blocks ON (blocks.blocker_id = sqlc.arg(user_id) AND blocks.blocked_id = conversation_members.user_id)
+    OR (blocks.blocker_id = conversation_members.user_id AND blocks.blocked_id = sqlc.arg(user_id))
+    WHERE conversation_members.conversation_id = sqlc.arg(conversation_id)
+);
+
+-- name: ListConversations :many
+SELECT
+    conversations.id,
+    conversations.created_at,
+    conversations.updated_at,
+    conversations.created_by,
+    (
+        SELECT array_agg(members.user_id ORDER BY members.joined_at, members.user_id)
+        FROM conversation_members AS members
+        WHERE members.conversation_id = conversations.id
+    )::uuid[] AS member_ids,
+    (
+        SELECT COUNT(*) FROM messages
+        WHERE messages.conversation_id = conversations.id
+        AND messages.sender_id <> sqlc.arg(user_id)
+        AND messages.created_at > COALESCE(conversation_members.last_read_at, '-infinity'::timestamp)
+        AND NOT EXISTS (
+            SELECT 1 FROM blocks
+            WHERE (blocks.blocker_id = sqlc.arg(user_id) AND blocks.blocked_id = messages.sender_id)
+            OR (blocks.blocker_id = messages.sender_id AND blocks.blocked_id = sqlc.arg(user_id))
+        )
+    )::bigint AS unread_count
+FROM conversations
+JOIN conversation_members ON conversation_members.conversation_id = conversations.id
+WHERE conversation_members.user_id = sqlc.arg(user_id)
+AND (conversations.updated_at, conversations.id) < (sqlc.arg(before)::timestamp, sqlc.arg(before_id)::uuid)
+ORDER BY conversations.updated_at DESC, conversations.id DESC
+LIMIT sqlc.arg(max_results);
+
+-- name: TouchConversation :exec
+UPDATE conversations
+SET updated_at = $2
+WHERE id = $1;
+
+-- name: MarkConversationRead :exec
+UPDATE conversation_members
+SET last_read_at = $3
+WHERE conversation_id = $1 AND user_id = $2;
+
+-- name: DeleteEmptyConversations :exec
+DELETE FROM conversations
+WHERE NOT EXISTS (
+    SELECT 1 FROM conversation_members
+    WHERE conversation_members.conversation_id = conversations.id
+);
@@ -0,0 +1,37 @@
+-- name: CreateDataExport :one
+INSERT INTO data_exports (id, created_at, updated_at, user_id, expires_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+) RETURNING *;
+
+-- name: GetDataExport :one
+SELECT * FROM data_exports
+WHERE id = $1 AND user_id = $2;
+
+-- name: CompleteDataExport :one
+UPDATE data_exports
+SET status = 'ready', file_path = $1, expires_at = $2, updated_at = $3
+WHERE id = $4
+RETURNING *;
+
+-- name: FailDataExport :exec
+UPDATE data_exports
+SET status = 'failed', error = $1, expires_at = $2, updated_at = $3
+WHERE id = $4;
+
+-- name: DeleteExpiredDataExports :many
+DELETE FROM data_exports
+WHERE expires_at < $1
+RETURNING *;
+
+-- name: DeleteDataExportsOfPurgeableUsers :many
+DELETE FROM data_exports
+WHERE user_id IN (
+    SELECT id FROM users
+    WHERE deletion_requested_at IS NOT NULL AND deletion_requested_at < $1
+)
+RETURNING *;
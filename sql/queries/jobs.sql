@@ -0,0 +1,39 @@
+-- name: EnqueueJob :one
+INSERT INTO jobs (id, created_at, updated_at, kind, payload, max_attempts, run_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7
+) RETURNING *;
+
+-- name: ClaimDueJobs :many
+UPDATE jobs
+SET status = 'running', attempts = attempts + 1, locked_until = sqlc.arg(lease_until), updated_at = sqlc.arg(now)
+WHERE id IN (
+    SELECT id FROM jobs
+    WHERE (status = 'pending' AND run_at <= sqlc.arg(now))
+    OR (status = 'running' AND locked_until <= sqlc.arg(now))
+    ORDER BY run_at
+    LIMIT sqlc.arg(max_results)
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: FinishJob :exec
+UPDATE jobs
+SET status = $1, run_at = $2, last_error = $3, locked_until = NULL, updated_at = $4
+WHERE id = $5;
+
+-- name: ListJobs :many
+SELECT * FROM jobs
+WHERE sqlc.arg(status)::text = '' OR status = sqlc.arg(status)::text
+ORDER BY created_at DESC
+LIMIT sqlc.arg(max_results);
+
+-- name: PurgeFinishedJobs :exec
+DELETE FROM jobs
+WHERE status = 'succeeded' AND updated_at < $1;
@@ -0,0 +1,52 @@
+-- name: CreateList :one
+INSERT INTO lists (id, created_at, updated_at, owner_id, name, description, is_private)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7
+) RETURNING *;
+
+-- name: GetList :one
+SELECT * FROM lists
+WHERE id = $1;
+
+-- name: GetListsByOwner :many
+SELECT * FROM lists
+WHERE owner_id = sqlc.arg(owner_id) AND (NOT is_private OR sqlc.arg(include_private)::boolean)
+ORDER BY created_at DESC;
+
+-- name: UpdateList :one
+UPDATE lists
+SET name = $3, description = $4, is_private = $5, updated_at = $6
+WHERE id = $1 AND owner_id = $2
+RETURNING *;
+
+-- name: DeleteList :one
+DELETE FROM lists
+WHERE id = $1 AND owner_id = $2
+RETURNING *;
+
+-- name: AddListMember :exec
+INSERT INTO list_members (list_id, user_id, added_at)
+VALUES (
+    $1,
+    $2,
+    $3
+) ON CONFLICT DO NOTHING;
+
+-- name: RemoveListMember :exec
+DELETE FROM list_members
+WHERE list_id = $1 AND user_id = $2;
+
+-- name: GetListMembers :many
+SELECT * FROM list_members
+WHERE list_id = $1
+ORDER BY added_at, user_id;
+
+-- name: CountListMembers :one
+SELECT COUNT(*) FROM list_members
+WHERE list_id = $1;
@@ -0,0 +1,26 @@
+-- name: CreateMessage :one
+INSERT INTO messages (id, created_at, conversation_id, sender_id, body)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+) RETURNING *;
+
+-- name: GetMessages :many
+SELECT messages.* FROM messages
+WHERE messages.conversation_id = sqlc.arg(conversation_id)
+AND NOT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocks.blocker_id = sqlc.arg(viewer_id) AND blocks.blocked_id = messages.sender_id)
+    OR (blocks.blocker_id = messages.sender_id AND blocks.blocked_id = sqlc.arg(viewer_id))
+)
+AND (messages.created_at, messages.id) < (sqlc.arg(before)::timestamp, sqlc.arg(before_id)::uuid)
+ORDER BY messages.created_at DESC, messages.id DESC
+LIMIT sqlc.arg(max_results);
+
+-- name: GetMessagesBySender :many
+SELECT * FROM messages
+WHERE sender_id = $1
+ORDER BY created_at, id;
@@ -0,0 +1,101 @@
+-- name: CreateNotification :execrows
+INSERT INTO notifications (id, created_at, user_id, actor_id, type, chirp_id)
+SELECT sqlc.arg(id)::uuid, sqlc.arg(created_at)::timestamp, sqlc.arg(user_id)::uuid, sqlc.arg(actor_id)::uuid, sqlc.arg(type)::text, sqlc.narg(chirp_id)::uuid
+WHERE NOT EXISTS (
+    SELECT 1 FROM notification_preferences
+    WHERE notification_preferences.user_id = sqlc.arg(user_id)::uuid
+    AND notification_preferences.type = sqlc.arg(type)::text
+    AND NOT notification_preferences.enabled
+)
+AND NOT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocks.blocker_id = sqlc.arg(user_id)::uuid AND blocks.blocked_id = sqlc.arg(actor_id)::uuid)
+    OR (blocks.blocker_id = sqlc.arg(actor_id)::uuid AND blocks.blocked_id = sqlc.arg(user_id)::uuid)
+)
+AND EXISTS (
+    SELECT 1 FROM users
+    WHERE users.id = sqlc.arg(user_id)::uuid AND users.deletion_requested_at IS NULL
+)
+ON CONFLICT DO NOTHING;
+
+-- name: GetNotification :one
+SELECT id, created_at, user_id, actor_id, type, chirp_id, read_at FROM notifications
+WHERE id = sqlc.arg(id) AND user_id = sqlc.arg(user_id);
+
+-- name: GetNotificationGroups :many
+SELECT type, chirp_id, latest_id, latest_at, actor_count, recent_actor_ids, unread
+FROM (
+    SELECT
+        notifications.type,
+        notifications.chirp_id,
+        (array_agg(notifications.id ORDER BY notifications.created_at DESC))[1]::uuid AS latest_id,
+        MAX(notifications.created_at)::timestamp AS latest_at,
+        COUNT(*) AS actor_count,
+        (array_agg(notifications.actor_id ORDER BY notifications.created_at DESC))[1:3]::uuid[] AS recent_actor_ids,
+        bool_or(notifications.read_at IS NULL)::boolean AS unread
+    FROM notifications
+    WHERE notifications.user_id = sqlc.arg(user_id)
+    AND NOT EXISTS (
+        SELECT 1 FROM blocks
+        WHERE (blocks.blocker_id = notifications.user_id AND blocks.blocked_id = notifications.actor_id)
+        OR (blocks.blocker_id = notifications.actor_id AND blocks.blocked_id = notifications.user_id)
+    )
+    AND NOT EXISTS (
+        SELECT 1 FROM mutes
+        WHERE mutes.muter_id = notifications.user_id AND mutes.muted_id = notifications.actor_id
+    )
+    AND NOT EXISTS (
+        SELECT 1 FROM chirps
+        WHERE chirps.id = notifications.chirp_id AND chirps.deleted_at IS NOT NULL
+    )
+    GROUP BY notifications.type, notifications.chirp_id
+) AS grouped
+WHERE (latest_at, latest_id) < (sqlc.arg(before)::timestamp, sqlc.arg(before_id)::uuid)
+ORDER BY latest_at DESC, latest_id DESC
+LIMIT sqlc.arg(max_results);
+
+-- name: CountUnreadNotificationGroups :one
+SELECT COUNT(DISTINCT notifications.type || ':' || COALESCE(notifications.chirp_id::text, '')) FROM notifications
+WHERE notifications.user_id = sqlc.arg(user_id) AND notifications.read_at IS NULL
+AND NOT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocks.blocker_id = notifications.user_id AND blocks.blocked_id = notifications.actor_id)
+    OR (blocks.blocker_id = notifications.actor_id AND blocks.blocked_id = notifications.user_id)
+)
+AND NOT EXISTS (
+    SELECT 1 FROM mutes
+    WHERE mutes.muter_id = notifications.user_id AND mutes.muted_id = notifications.actor_id
+)
+AND NOT EXISTS (
+    SELECT 1 FROM chirps
+    WHERE chirps.id = notifications.chirp_id AND chirps.deleted_at IS NOT NULL
+);
+
+-- name: MarkNotificationGroupRead :exec
+UPDATE notifications
+SET read_at = sqlc.arg(read_at)
+FROM notifications AS target
+WHERE target.id = sqlc.arg(id) AND target.user_id = sqlc.arg(user_id)
+AND notifications.user_id = target.user_id
+AND notifications.type = target.type
+AND notifications.chirp_id IS NOT DISTINCT FROM target.chirp_id
+AND notifications.created_at <= target.created_at
+AND notifications.read_at IS NULL;
+
+-- name: MarkAllNotificationsRead :exec
+UPDATE notifications
+SET read_at = sqlc.arg(read_at)
+WHERE user_id = sqlc.arg(user_id) AND read_at IS NULL;
+
+-- name: GetNotificationPreferences :many
+SELECT user_id, type, enabled FROM notification_preferences
+WHERE user_id = sqlc.arg(user_id)
+ORDER BY type;
+
+-- name: SetNotificationPreference :exec
+INSERT INTO notification_preferences (user_id, type, enabled)
+VALUES (
+    sqlc.arg(user_id),
+    sqlc.arg(type),
+    sqlc.arg(enabled)
+) ON CONFLICT (user_id, type) DO UPDATE SET enabled = EXCLUDED.enabled;
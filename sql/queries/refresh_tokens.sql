@@ -18,3 +18,13 @@ UPDATE refresh_tokens
 SET revoked_at = $1
 WHERE tokens = $2
 RETURNING *;
+
+-- name: RevokeUserTokens :exec
+UPDATE refresh_tokens
+SET revoked_at = $1
+WHERE user_id = $2 AND revoked_at IS NULL;
+
+-- name: GetUserRefreshTokens :many
+SELECT * FROM refresh_tokens
+WHERE user_id = $1
+ORDER BY created_at;
@@ -0,0 +1,41 @@
+-- name: LockStreamEventOrder :exec
+SELECT pg_advisory_xact_lock(hashtext('stream_events'));
+
+-- name: CreateStreamEvent :one
+INSERT INTO stream_events (created_at, event, actor_id, topics, data)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+) RETURNING id;
+
+-- name: NotifyStreamEvent :exec
+SELECT pg_notify('stream_events', sqlc.arg(id)::bigint::text);
+
+-- name: NotifyTyping :exec
+SELECT pg_notify('typing', sqlc.arg(payload)::text);
+
+-- name: GetStreamEvent :one
+SELECT * FROM stream_events
+WHERE id = $1;
+
+-- name: GetStreamEventsAfter :many
+SELECT * FROM stream_events
+WHERE id > sqlc.arg(after_id) AND sqlc.arg(topic)::text = ANY(topics)
+ORDER BY id
+LIMIT sqlc.arg(max_results);
+
+-- name: GetAllStreamEventsAfter :many
+SELECT * FROM stream_events
+WHERE id > $1
+ORDER BY id
+LIMIT $2;
+
+-- name: GetLatestStreamEventID :one
+SELECT COALESCE(MAX(id), 0)::bigint FROM stream_events;
+
+-- name: PurgeStreamEvents :exec
+DELETE FROM stream_events
+WHERE created_at < $1;
@@ -0,0 +1,39 @@
+-- name: UpsertSubscription :one
+INSERT INTO subscriptions (user_id, created_at, updated_at, plan, status, current_period_end, canceled_at, last_event_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8
+)
+ON CONFLICT (user_id) DO UPDATE
+SET updated_at = EXCLUDED.updated_at,
+    plan = EXCLUDED.plan,
+    status = EXCLUDED.status,
+    current_period_end = EXCLUDED.current_period_end,
+    canceled_at = EXCLUDED.canceled_at,
+    last_event_at = EXCLUDED.last_event_at
+RETURNING *;
+
+-- name: LockSubscription :exec
+SELECT pg_advisory_xact_lock(hashtext('subscriptions'), hashtext(sqlc.arg(user_id)::uuid::text));
+
+-- name: GetSubscription :one
+SELECT * FROM subscriptions
+WHERE user_id = $1;
+
+-- name: ExpireLapsedSubscriptions :many
+WITH lapsed AS (
+    UPDATE subscriptions
+    SET status = 'expired', updated_at = sqlc.arg(now)
+    WHERE status <> 'expired' AND current_period_end <= sqlc.arg(now)
+    RETURNING user_id
+)
+UPDATE users
+SET is_chirpy_red = FALSE
+WHERE id IN (SELECT user_id FROM lapsed)
+RETURNING id;
@@ -0,0 +1,80 @@
+-- name: LockTrendingPeriod :exec
+SELECT pg_advisory_xact_lock(hashtext('trends'), hashtext(sqlc.arg(period)::text));
+
+-- name: RefreshTrendingHashtags :exec
+INSERT INTO trending_hashtags (period, hashtag, score, chirp_count, author_count, computed_at)
+SELECT
+    sqlc.arg(period)::text,
+    tagged.hashtag,
+    SUM(power(0.5, EXTRACT(EPOCH FROM (sqlc.arg(computed_at)::timestamp - tagged.posted_at)) / sqlc.arg(half_life_seconds)::float8))::float8 AS score,
+    COUNT(*) AS chirp_count,
+    COUNT(DISTINCT tagged.user_id) AS author_count,
+    sqlc.arg(computed_at)::timestamp
+FROM (
+    SELECT DISTINCT chirps.id, chirps.user_id, COALESCE(chirps.publish_at, chirps.created_at) AS posted_at, lower(hashtag.match[1]) AS hashtag
+    FROM chirps
+    JOIN users ON users.id = chirps.user_id
+    CROSS JOIN LATERAL regexp_matches(chirps.body, '#([[:alnum:]_]+)', 'g') AS hashtag(match)
+    WHERE chirps.published AND chirps.deleted_at IS NULL AND users.deletion_requested_at IS NULL
+    AND chirps.visibility = 'public'
+    AND COALESCE(chirps.publish_at, chirps.created_at) > sqlc.arg(since)::timestamp
+) AS tagged
+WHERE NOT EXISTS (
+    SELECT 1 FROM trending_hashtags
+    WHERE trending_hashtags.period = sqlc.arg(period)::text AND trending_hashtags.computed_at > sqlc.arg(computed_at)::timestamp
+)
+GROUP BY tagged.hashtag
+ORDER BY score DESC, tagged.hashtag
+LIMIT sqlc.arg(max_results)
+ON CONFLICT (period, hashtag) DO UPDATE
+SET score = EXCLUDED.score, chirp_count = EXCLUDED.chirp_count, author_count = EXCLUDED.author_count, computed_at = EXCLUDED.computed_at
+WHERE trending_hashtags.computed_at < EXCLUDED.computed_at;
+
+-- name: RefreshTrendingChirps :exec
+INSERT INTO trending_chirps (period, chirp_id, score, computed_at)
+SELECT
+    sqlc.arg(period)::text,
+    chirps.id,
+    (floor(SUM(power(0.5, EXTRACT(EPOCH FROM (sqlc.arg(computed_at)::timestamp - bookmarks.created_at)) / sqlc.arg(half_life_seconds)::float8)) / sqlc.arg(score_step)::float8) * sqlc.arg(score_step)::float8)::float8 AS score,
+    sqlc.arg(computed_at)::timestamp
+FROM bookmarks
+JOIN chirps ON chirps.id = bookmarks.chirp_id
+JOIN users ON users.id = chirps.user_id
+WHERE bookmarks.created_at > sqlc.arg(since)::timestamp AND bookmarks.user_id <> chirps.user_id
+AND chirps.published AND chirps.deleted_at IS NULL AND users.deletion_requested_at IS NULL
+AND chirps.visibility = 'public'
+AND NOT EXISTS (
+    SELECT 1 FROM trending_chirps
+    WHERE trending_chirps.period = sqlc.arg(period)::text AND trending_chirps.computed_at > sqlc.arg(computed_at)::timestamp
+)
+GROUP BY chirps.id
+HAVING SUM(power(0.5, EXTRACT(EPOCH FROM (sqlc.arg(computed_at)::timestamp - bookmarks.created_at)) / sqlc.arg(half_life_seconds)::float8)) >= sqlc.arg(score_step)::float8
+ORDER BY score DESC, chirps.id
+LIMIT sqlc.arg(max_results)
+ON CONFLICT (period, chirp_id) DO UPDATE
+SET score = EXCLUDED.score, computed_at = EXCLUDED.computed_at
+WHERE trending_chirps.computed_at < EXCLUDED.computed_at;
+
+-- name: DeleteStaleTrendingHashtags :exec
+DELETE FROM trending_hashtags
+WHERE period = $1 AND computed_at < $2;
+
+-- name: DeleteStaleTrendingChirps :exec
+DELETE FROM trending_chirps
+WHERE period = $1 AND computed_at < $2;
+
+-- name: GetTrendingHashtags :many
+SELECT * FROM trending_hashtags
+WHERE period = sqlc.arg(period)
+ORDER BY score DESC, hashtag
+LIMIT sqlc.arg(max_results);
+
+-- name: GetTrendingChirps :many
+SELECT sqlc.embed(chirps), trending_chirps.score, trending_chirps.computed_at
+FROM trending_chirps
+JOIN chirps ON chirps.id = trending_chirps.chirp_id
+JOIN users ON users.id = chirps.user_id
+WHERE trending_chirps.period = sqlc.arg(period)
+AND chirps.published AND chirps.deleted_at IS NULL AND users.deletion_requested_at IS NULL
+ORDER BY trending_chirps.score DESC, trending_chirps.chirp_id
+LIMIT sqlc.arg(max_results);
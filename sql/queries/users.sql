@@ -31,8 +31,44 @@ SELECT updated_user.id, updated_user.email, refresh_tokens.tokens, updated_user.
 FROM updated_user
 LEFT JOIN refresh_tokens ON updated_user.id = refresh_tokens.user_id;
 
--- name: UpgradeUsertoRed :one
+-- name: SetChirpyRed :exec
 UPDATE users
-SET is_chirpy_red = TRUE
-WHERE id = $1
+SET is_chirpy_red = $1
+WHERE id = $2;
+
+-- name: SetUserRole :one
+UPDATE users
+SET role = $1, updated_at = $2
+WHERE id = $3
+RETURNING *;
+
+-- name: SetUserHideSensitive :one
+UPDATE users
+SET hide_sensitive = $1, updated_at = $2
+WHERE id = $3
 RETURNING *;
+
+-- name: CountUsersWithRole :one
+SELECT COUNT(*) FROM users
+WHERE role = $1;
+
+-- name: RequestUserDeletion :one
+UPDATE users
+SET deletion_requested_at = $1, updated_at = $2
+WHERE id = $3
+RETURNING *;
+
+-- name: CancelUserDeletion :exec
+UPDATE users
+SET deletion_requested_at = NULL, updated_at = $1
+WHERE id = $2 AND deletion_requested_at IS NOT NULL;
+
+-- name: PurgeUsersPendingDeletion :many
+DELETE FROM users
+WHERE deletion_requested_at IS NOT NULL AND deletion_requested_at < $1
+RETURNING id;
+
+-- name: UpdatePasswordHash :exec
+UPDATE users
+SET password = $1
+WHERE id = $2;
@@ -0,0 +1,35 @@
+-- name: CreateWebhookEvent :one
+INSERT INTO webhook_events (id, created_at, updated_at, event, payload, sent_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
+)
+ON CONFLICT (id) DO NOTHING
+RETURNING *;
+
+-- name: GetWebhookEvent :one
+SELECT * FROM webhook_events
+WHERE id = $1;
+
+-- name: ClaimWebhookEvent :one
+UPDATE webhook_events
+SET status = 'processing', attempts = attempts + 1, updated_at = sqlc.arg(updated_at)
+WHERE id = sqlc.arg(id)
+AND (status IN ('pending', 'failed') OR (status = 'processing' AND updated_at < sqlc.arg(stale_before)))
+RETURNING *;
+
+-- name: FinishWebhookEvent :one
+UPDATE webhook_events
+SET status = $1, error = $2, updated_at = $3
+WHERE id = $4
+RETURNING *;
+
+-- name: ListWebhookEvents :many
+SELECT * FROM webhook_events
+WHERE sqlc.arg(status)::text = '' OR status = sqlc.arg(status)::text
+ORDER BY created_at DESC
+LIMIT sqlc.arg(max_results);
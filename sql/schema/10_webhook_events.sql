@@ -0,0 +1,17 @@
+-- +goose Up
+CREATE TABLE webhook_events (
+id TEXT PRIMARY KEY,
+created_at TIMESTAMP NOT NULL,
+updated_at TIMESTAMP NOT NULL,
+event TEXT NOT NULL,
+payload TEXT NOT NULL,
+status TEXT NOT NULL DEFAULT 'pending'
+CHECK (status IN ('pending', 'processing', 'processed', 'failed', 'ignored')),
+error TEXT,
+attempts INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX webhook_events_status_idx ON webhook_events(status, created_at);
+
+-- +goose Down
+DROP TABLE webhook_events;
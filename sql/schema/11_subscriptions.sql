@@ -0,0 +1,22 @@
+-- +goose Up
+CREATE TABLE subscriptions (
+user_id UUID PRIMARY KEY,
+created_at TIMESTAMP NOT NULL,
+updated_at TIMESTAMP NOT NULL,
+plan TEXT NOT NULL,
+status TEXT NOT NULL
+CHECK (status IN ('active', 'past_due', 'canceled', 'expired')),
+current_period_end TIMESTAMP,
+canceled_at TIMESTAMP,
+FOREIGN KEY(user_id)
+REFERENCES users(id)
+ON DELETE CASCADE
+);
+
+-- Existing Red users keep an open ended subscription.
+INSERT INTO subscriptions (user_id, created_at, updated_at, plan, status)
+SELECT id, NOW(), NOW(), 'red', 'active' FROM users
+WHERE is_chirpy_red;
+
+-- +goose Down
+DROP TABLE subscriptions;
@@ -0,0 +1,40 @@
+-- +goose Up
+CREATE TABLE webhook_endpoints (
+id UUID PRIMARY KEY,
+created_at TIMESTAMP NOT NULL,
+updated_at TIMESTAMP NOT NULL,
+user_id UUID NOT NULL,
+url TEXT NOT NULL,
+secret TEXT NOT NULL,
+events TEXT[] NOT NULL,
+global BOOLEAN NOT NULL DEFAULT FALSE,
+FOREIGN KEY(user_id)
+REFERENCES users(id)
+ON DELETE CASCADE
+);
+
+CREATE TABLE webhook_deliveries (
+id UUID PRIMARY KEY,
+created_at TIMESTAMP NOT NULL,
+updated_at TIMESTAMP NOT NULL,
+endpoint_id UUID NOT NULL,
+event_id UUID NOT NULL,
+event TEXT NOT NULL,
+payload TEXT NOT NULL,
+status TEXT NOT NULL DEFAULT 'pending'
+CHECK (status IN ('pending', 'succeeded', 'dead')),
+attempts INTEGER NOT NULL DEFAULT 0,
+next_attempt_at TIMESTAMP NOT NULL,
+last_error TEXT,
+response_status INTEGER,
+FOREIGN KEY(endpoint_id)
+REFERENCES webhook_endpoints(id)
+ON DELETE CASCADE
+);
+
+CREATE INDEX webhook_deliveries_due_idx ON webhook_deliveries(next_attempt_at)
+WHERE status = 'pending';
+
+-- +goose Down
+DROP TABLE webhook_deliveries;
+DROP TABLE webhook_endpoints;
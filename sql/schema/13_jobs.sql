@@ -0,0 +1,21 @@
+-- +goose Up
+CREATE TABLE jobs (
+id UUID PRIMARY KEY,
+created_at TIMESTAMP NOT NULL,
+updated_at TIMESTAMP NOT NULL,
+kind TEXT NOT NULL,
+payload TEXT NOT NULL,
+status TEXT NOT NULL DEFAULT 'pending'
+CHECK (status IN ('pending', 'running', 'succeeded', 'dead')),
+attempts INTEGER NOT NULL DEFAULT 0,
+max_attempts INTEGER NOT NULL,
+run_at TIMESTAMP NOT NULL,
+locked_until TIMESTAMP,
+last_error TEXT
+);
+
+CREATE INDEX jobs_due_idx ON jobs(run_at)
+WHERE status IN ('pending', 'running');
+
+-- +goose Down
+DROP TABLE jobs;
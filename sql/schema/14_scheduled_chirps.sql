@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE chirps
+ADD COLUMN published BOOLEAN NOT NULL DEFAULT TRUE,
+ADD COLUMN publish_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE chirps
+DROP COLUMN publish_at,
+DROP COLUMN published;
@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE drafts (
+id UUID PRIMARY KEY,
+created_at TIMESTAMP NOT NULL,
+updated_at TIMESTAMP NOT NULL,
+user_id UUID NOT NULL,
+body TEXT NOT NULL,
+FOREIGN KEY(user_id)
+REFERENCES users(id)
+ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE drafts;
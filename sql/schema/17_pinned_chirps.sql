@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE chirps
+ADD COLUMN pinned_position INTEGER;
+
+CREATE UNIQUE INDEX chirps_pinned_position_idx ON chirps(user_id, pinned_position)
+WHERE pinned_position IS NOT NULL;
+
+-- +goose Down
+DROP INDEX chirps_pinned_position_idx;
+ALTER TABLE chirps
+DROP COLUMN pinned_position;
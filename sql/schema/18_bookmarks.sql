@@ -0,0 +1,18 @@
+-- +goose Up
+CREATE TABLE bookmarks (
+user_id UUID NOT NULL,
+chirp_id UUID NOT NULL,
+created_at TIMESTAMP NOT NULL,
+PRIMARY KEY (user_id, chirp_id),
+FOREIGN KEY(user_id)
+REFERENCES users(id)
+ON DELETE CASCADE,
+FOREIGN KEY(chirp_id)
+REFERENCES chirps(id)
+ON DELETE CASCADE
+);
+
+CREATE INDEX bookmarks_user_created_idx ON bookmarks(user_id, created_at DESC, chirp_id DESC);
+
+-- +goose Down
+DROP TABLE bookmarks;
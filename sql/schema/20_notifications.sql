@@ -0,0 +1,38 @@
+-- +goose Up
+CREATE TABLE notifications (
+id UUID PRIMARY KEY,
+created_at TIMESTAMP NOT NULL,
+user_id UUID NOT NULL,
+actor_id UUID NOT NULL,
+type TEXT NOT NULL,
+chirp_id UUID,
+read_at TIMESTAMP,
+FOREIGN KEY(user_id)
+REFERENCES users(id)
+ON DELETE CASCADE,
+FOREIGN KEY(actor_id)
+REFERENCES users(id)
+ON DELETE CASCADE,
+FOREIGN KEY(chirp_id)
+REFERENCES chirps(id)
+ON DELETE CASCADE
+);
+
+-- An actor notifies a user once per thing they did, so liking, unliking and
+-- liking again doesn't notify twice.
+CREATE UNIQUE INDEX notifications_once_idx ON notifications(user_id, actor_id, type, COALESCE(chirp_id, '00000000-0000-0000-0000-000000000000'::uuid));
+CREATE INDEX notifications_user_created_idx ON notifications(user_id, created_at DESC);
+
+CREATE TABLE notification_preferences (
+user_id UUID NOT NULL,
+type TEXT NOT NULL,
+enabled BOOLEAN NOT NULL,
+PRIMARY KEY (user_id, type),
+FOREIGN KEY(user_id)
+REFERENCES users(id)
+ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE notification_preferences;
+DROP TABLE notifications;
@@ -0,0 +1,15 @@
+-- +goose Up
+CREATE TABLE stream_events (
+id BIGSERIAL PRIMARY KEY,
+created_at TIMESTAMP NOT NULL,
+event TEXT NOT NULL,
+actor_id UUID NOT NULL,
+topics TEXT[] NOT NULL,
+data TEXT NOT NULL
+);
+
+CREATE INDEX stream_events_topics_idx ON stream_events USING GIN (topics);
+CREATE INDEX stream_events_created_idx ON stream_events(created_at);
+
+-- +goose Down
+DROP TABLE stream_events;
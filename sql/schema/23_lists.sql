@@ -0,0 +1,35 @@
+-- +goose Up
+CREATE TABLE lists (
+id UUID PRIMARY KEY,
+created_at TIMESTAMP NOT NULL,
+updated_at TIMESTAMP NOT NULL,
+owner_id UUID NOT NULL,
+name TEXT NOT NULL,
+description TEXT NOT NULL DEFAULT '',
+is_private BOOLEAN NOT NULL DEFAULT FALSE,
+FOREIGN KEY(owner_id)
+REFERENCES users(id)
+ON DELETE CASCADE
+);
+
+CREATE INDEX lists_owner_idx ON lists(owner_id);
+
+CREATE TABLE list_members (
+list_id UUID NOT NULL,
+user_id UUID NOT NULL,
+added_at TIMESTAMP NOT NULL,
+PRIMARY KEY (list_id, user_id),
+FOREIGN KEY(list_id)
+REFERENCES lists(id)
+ON DELETE CASCADE,
+FOREIGN KEY(user_id)
+REFERENCES users(id)
+ON DELETE CASCADE
+);
+
+CREATE INDEX chirps_user_created_idx ON chirps(user_id, created_at DESC, id DESC);
+
+-- +goose Down
+DROP INDEX chirps_user_created_idx;
+DROP TABLE list_members;
+DROP TABLE lists;
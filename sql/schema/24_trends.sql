@@ -0,0 +1,30 @@
+-- +goose Up
+CREATE TABLE trending_hashtags (
+period TEXT NOT NULL,
+hashtag TEXT NOT NULL,
+score DOUBLE PRECISION NOT NULL,
+chirp_count BIGINT NOT NULL,
+author_count BIGINT NOT NULL,
+computed_at TIMESTAMP NOT NULL,
+PRIMARY KEY (period, hashtag)
+);
+
+CREATE TABLE trending_chirps (
+period TEXT NOT NULL,
+chirp_id UUID NOT NULL,
+score DOUBLE PRECISION NOT NULL,
+computed_at TIMESTAMP NOT NULL,
+PRIMARY KEY (period, chirp_id),
+FOREIGN KEY(chirp_id)
+REFERENCES chirps(id)
+ON DELETE CASCADE
+);
+
+CREATE INDEX chirps_posted_at_idx ON chirps((COALESCE(publish_at, created_at)));
+CREATE INDEX bookmarks_created_idx ON bookmarks(created_at);
+
+-- +goose Down
+DROP INDEX bookmarks_created_idx;
+DROP INDEX chirps_posted_at_idx;
+DROP TABLE trending_chirps;
+DROP TABLE trending_hashtags;
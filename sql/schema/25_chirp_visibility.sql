@@ -0,0 +1,8 @@
+-- +goose Up
+ALTER TABLE chirps
+ADD COLUMN visibility TEXT NOT NULL DEFAULT 'public'
+CHECK (visibility IN ('public', 'followers', 'unlisted'));
+
+-- +goose Down
+ALTER TABLE chirps
+DROP COLUMN visibility;
@@ -0,0 +1,14 @@
+-- +goose Up
+-- When Polka sent each event, taken from its signed timestamp, so events
+-- are applied in the order they happened rather than the order they arrived.
+ALTER TABLE webhook_events
+ADD COLUMN sent_at TIMESTAMP;
+
+ALTER TABLE subscriptions
+ADD COLUMN last_event_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE subscriptions
+DROP COLUMN last_event_at;
+ALTER TABLE webhook_events
+DROP COLUMN sent_at;
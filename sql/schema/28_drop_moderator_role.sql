@@ -0,0 +1,13 @@
+-- +goose Up
+-- Nothing was ever gated on the moderator role, so moderators go back to
+-- being users.
+UPDATE users SET role = 'user' WHERE role = 'moderator';
+
+ALTER TABLE users
+DROP CONSTRAINT users_role_check,
+ADD CONSTRAINT users_role_check CHECK (role IN ('user', 'admin'));
+
+-- +goose Down
+ALTER TABLE users
+DROP CONSTRAINT users_role_check,
+ADD CONSTRAINT users_role_check CHECK (role IN ('user', 'moderator', 'admin'));
@@ -0,0 +1,17 @@
+-- +goose Up
+CREATE TABLE user_identities (
+id UUID PRIMARY KEY,
+created_at TIMESTAMP NOT NULL,
+updated_at TIMESTAMP NOT NULL,
+user_id UUID NOT NULL,
+issuer TEXT NOT NULL,
+subject TEXT NOT NULL,
+email TEXT NOT NULL,
+UNIQUE(issuer, subject),
+FOREIGN KEY(user_id)
+REFERENCES users(id)
+ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE user_identities;
@@ -0,0 +1,6 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN deletion_requested_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE users DROP deletion_requested_at;
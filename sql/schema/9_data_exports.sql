@@ -0,0 +1,17 @@
+-- +goose Up
+CREATE TABLE data_exports (
+id UUID PRIMARY KEY,
+created_at TIMESTAMP NOT NULL,
+updated_at TIMESTAMP NOT NULL,
+user_id UUID NOT NULL,
+status TEXT NOT NULL DEFAULT 'pending',
+file_path TEXT,
+error TEXT,
+expires_at TIMESTAMP,
+FOREIGN KEY(user_id)
+REFERENCES users(id)
+ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE data_exports;
@@ -0,0 +1,390 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anantashahane/Chirpy/internal/database"
+	"github.com/anantashahane/Chirpy/internal/pubsub"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	// How many events a stream client may fall behind before it is cut off.
+	// It can reconnect with Last-Event-ID and catch up from the database.
+	streamBuffer = 64
+	// How many missed events a reconnecting client is sent at most.
+	streamReplayLimit = 1000
+	streamHeartbeat   = 15 * time.Second
+	streamRetention   = 24 * time.Hour
+)
+
+// hashtagPattern matches tags of letters, digits and underscores in any
+// script. trends.sql spells the same class as [[:alnum:]_]; keep the two in
+// step or streams and trends disagree on what a hashtag is.
+var hashtagPattern = regexp.MustCompile(`#([\p{L}\p{Nd}_]+)`)
+
+// chirpHashtags returns the distinct hashtags in body, lower cased and
+// without the #.
+func chirpHashtags(body string) []string {
+	tags := []string{}
+	for _, match := range hashtagPattern.FindAllStringSubmatch(body, -1) {
+		tag := strings.ToLower(match[1])
+		if !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// publishStreamEvent records event for chirp's public feed, author and
+// hashtag streams through q.
+func publishStreamEvent(ctx context.Context, q *database.Queries, event string, chirp database.Chirp, data any) error {
+	if chirp.Visibility != visibilityPublic {
+		return nil
+	}
+	topics := []string{"public", "user:" + chirp.UserID.String()}
+	for _, tag := range chirpHashtags(chirp.Body) {
+		topics = append(topics, "hashtag:"+tag)
+	}
+	return recordStreamEvent(ctx, q, event, chirp.UserID, topics, data)
+}
+
+// recordStreamEvent stores event for the given topics through q and notifies
+// every replica once q's transaction commits. q must be bound to a
+// transaction: it holds a lock from taking the event's ID until commit, so
+// IDs become visible in order and subscribers can resume from the last ID
+// they saw without skipping a slower transaction's event.
+func recordStreamEvent(ctx context.Context, q *database.Queries, event string, actorID uuid.UUID, topics []string, data any) error {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if err := q.LockStreamEventOrder(ctx); err != nil {
+		return err
+	}
+	id, err := q.CreateStreamEvent(ctx, database.CreateStreamEventParams{
+		CreatedAt: time.Now(),
+		Event:     event,
+		ActorID:   actorID,
+		Topics:    topics,
+		Data:      string(encoded),
+	})
+	if err != nil {
+		return err
+	}
+	return q.NotifyStreamEvent(ctx, id)
+}
+
+// streamCursor is the ID of the last stream event a subscriber was sent.
+// Stream events commit in ID order (see recordStreamEvent), so anything at
+// or below it was sent already.
+type streamCursor int64
+
+// advance moves the cursor to id, reporting false if id was already sent.
+// Events without an ID, like typing indicators, always pass.
+func (cursor *streamCursor) advance(id int64) bool {
+	if id == 0 {
+		return true
+	}
+	if id <= int64(*cursor) {
+		return false
+	}
+	*cursor = streamCursor(id)
+	return true
+}
+
+func streamEvent(event database.StreamEvent) pubsub.Event {
+	return pubsub.Event{
+		ID:      event.ID,
+		Type:    event.Event,
+		Topics:  event.Topics,
+		ActorID: event.ActorID,
+		Data:    []byte(event.Data),
+	}
+}
+
+// sensitiveEvent reports whether event carries a chirp behind a content
+// warning or marked sensitive, which viewers hiding sensitive chirps skip.
+func sensitiveEvent(event pubsub.Event) bool {
+	chirp := struct {
+		ContentWarning string `json:"content_warning"`
+		Sensitive      bool   `json:"sensitive"`
+	}{}
+	if err := json.Unmarshal(event.Data, &chirp); err != nil {
+		return false
+	}
+	return chirp.Sensitive || chirp.ContentWarning != ""
+}
+
+// listenStreamEvents feeds this replica's hub from Postgres. Every
+// notification carries the ID of a committed stream event. After the
+// connection drops, events recorded in the meantime are caught up on.
+func (cfg *apiConfig) listenStreamEvents(ctx context.Context, dbURL string) {
+	listener := pq.NewListener(dbURL, time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			fmt.Println("Stream listener: " + err.Error())
+		}
+	})
+	defer listener.Close()
+	if err := listener.Listen("stream_events"); err != nil {
+		fmt.Println("Streaming disabled, " + err.Error())
+		return
+	}
+	if err := listener.Listen("typing"); err != nil {
+		fmt.Println("Typing indicators disabled, " + err.Error())
+	}
+
+	lastID, err := cfg.db.GetLatestStreamEventID(ctx)
+	if err != nil {
+		fmt.Println("Stream listener: " + err.Error())
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case notification := <-listener.Notify:
+			if notification == nil {
+				// Reconnected, notifications sent while away are lost.
+				lastID = cfg.catchUpStreamEvents(ctx, lastID)
+				continue
+			}
+			if notification.Channel == "typing" {
+				cfg.publishTyping(notification.Extra)
+				continue
+			}
+			id, err := strconv.ParseInt(notification.Extra, 10, 64)
+			if err != nil {
+				continue
+			}
+			event, err := cfg.db.GetStreamEvent(ctx, id)
+			if err != nil {
+				fmt.Println("Stream listener: " + err.Error())
+				continue
+			}
+			cfg.streamHub.Publish(streamEvent(event))
+			lastID = max(lastID, event.ID)
+		case <-time.After(time.Minute):
+			go listener.Ping()
+		}
+	}
+}
+
+// typingIndicator says a member is typing in a conversation. It goes
+// straight to the hub without being stored, so it has no ID and is never
+// replayed.
+type typingIndicator struct {
+	ConversationID uuid.UUID `json:"conversation_id"`
+	UserID         uuid.UUID `json:"user_id"`
+}
+
+func (cfg *apiConfig) publishTyping(payload string) {
+	typing := typingIndicator{}
+	if err := json.Unmarshal([]byte(payload), &typing); err != nil {
+		return
+	}
+	cfg.streamHub.Publish(pubsub.Event{
+		Type:    "typing",
+		Topics:  []string{typingTopic(typing.ConversationID)},
+		ActorID: typing.UserID,
+		Data:    []byte(payload),
+	})
+}
+
+func typingTopic(conversationID uuid.UUID) string {
+	return "typing:" + conversationID.String()
+}
+
+func (cfg *apiConfig) catchUpStreamEvents(ctx context.Context, lastID int64) int64 {
+	events, err := cfg.db.GetAllStreamEventsAfter(ctx, database.GetAllStreamEventsAfterParams{
+		ID:    lastID,
+		Limit: streamReplayLimit,
+	})
+	if err != nil {
+		fmt.Println("Stream listener: " + err.Error())
+		return lastID
+	}
+	for _, event := range events {
+		cfg.streamHub.Publish(streamEvent(event))
+		lastID = event.ID
+	}
+	return lastID
+}
+
+func (cfg *apiConfig) purgeStreamEvents(ctx context.Context) {
+	err := cfg.db.PurgeStreamEvents(ctx, time.Now().Add(-streamRetention))
+	if err != nil {
+		fmt.Println("Stream event purge failed: " + err.Error())
+	}
+}
+
+// timelineTopic picks the stream of a user's chirps when userIDString is
+// set, of a hashtag when hashtag is, or the public feed. On failure it
+// returns the status to answer with.
+func (apiCfg *apiConfig) timelineTopic(viewerID uuid.UUID, userIDString, hashtag string) (string, int, error) {
+	hashtag = strings.ToLower(strings.TrimPrefix(hashtag, "#"))
+	if userIDString != "" && hashtag != "" {
+		return "", 400, errors.New("Stream a user or a hashtag, not both.")
+	}
+	if hashtag != "" {
+		return "hashtag:" + hashtag, 0, nil
+	}
+	if userIDString == "" {
+		return "public", 0, nil
+	}
+
+	userID, err := uuid.Parse(userIDString)
+	if err != nil {
+		return "", 400, errors.New("Error parsing user id.")
+	}
+	blocked, err := apiCfg.db.IsBlockedEitherWay(context.Background(), database.IsBlockedEitherWayParams{
+		UserID:  viewerID,
+		OtherID: userID,
+	})
+	if err != nil {
+		return "", 500, errors.New("Internal Server failed to access database.")
+	}
+	user, err := apiCfg.db.GetUserByID(context.Background(), userID)
+	if err != nil || user.DeletionRequestedAt.Valid || blocked {
+		return "", 404, errors.New("No such user " + userID.String())
+	}
+	return "user:" + user.ID.String(), 0, nil
+}
+
+// parseLastEventID reads the ID a reconnecting client saw last, zero when it
+// sent none.
+func parseLastEventID(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	lastEventID, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || lastEventID < 0 {
+		return 0, errors.New("Invalid Last-Event-ID.")
+	}
+	return lastEventID, nil
+}
+
+// streamHandler pushes chirp.created and chirp.deleted events as Server-Sent
+// Events. Signed in viewers skip authors they blocked, were blocked by or
+// muted, as of when they connected, and sensitive chirps are left out the
+// same way as in feeds.
+func (apiCfg *apiConfig) streamHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	viewerID := apiCfg.viewerFromRequest(req)
+	topic, status, err := apiCfg.timelineTopic(viewerID, req.URL.Query().Get("user_id"), req.URL.Query().Get("hashtag"))
+	if err != nil {
+		responseWriter.WriteHeader(status)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte(err.Error()))
+		return
+	}
+
+	lastEventID := req.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = req.URL.Query().Get("last_event_id")
+	}
+	lastSent, err := parseLastEventID(lastEventID)
+	if err != nil {
+		responseWriter.WriteHeader(400)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte(err.Error()))
+		return
+	}
+
+	hidden := []uuid.UUID{}
+	if viewerID != uuid.Nil && !strings.HasPrefix(topic, "user:") {
+		hidden, err = apiCfg.db.GetHiddenAuthorIDs(context.Background(), viewerID)
+		if err != nil {
+			responseWriter.WriteHeader(500)
+			responseWriter.Header().Set("Content-Type", "plain/text")
+			responseWriter.Write([]byte("Internal Server failed to access database."))
+			return
+		}
+	}
+
+	includeSensitive, status, err := apiCfg.includeSensitive(req, viewerID)
+	if err != nil {
+		responseWriter.WriteHeader(status)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte(err.Error()))
+		return
+	}
+
+	// Subscribe before replaying so nothing recorded in between is missed,
+	// the replay's IDs let the live events skip what was already sent.
+	sub := apiCfg.streamHub.Subscribe(topic, streamBuffer)
+	defer sub.Close()
+
+	controller := http.NewResponseController(responseWriter)
+	responseWriter.Header().Set("Content-Type", "text/event-stream")
+	responseWriter.Header().Set("Cache-Control", "no-cache")
+	responseWriter.Header().Set("X-Accel-Buffering", "no")
+	responseWriter.WriteHeader(200)
+	fmt.Fprint(responseWriter, "retry: 3000\n\n")
+
+	cursor := streamCursor(lastSent)
+	send := func(event pubsub.Event) error {
+		if !cursor.advance(event.ID) {
+			return nil
+		}
+		if slices.Contains(hidden, event.ActorID) {
+			return nil
+		}
+		if !includeSensitive && event.ActorID != viewerID && sensitiveEvent(event) {
+			return nil
+		}
+		_, err := fmt.Fprintf(responseWriter, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, event.Data)
+		return err
+	}
+
+	if lastSent > 0 {
+		missed, err := apiCfg.db.GetStreamEventsAfter(context.Background(), database.GetStreamEventsAfterParams{
+			AfterID:    lastSent,
+			Topic:      topic,
+			MaxResults: streamReplayLimit,
+		})
+		if err != nil {
+			return
+		}
+		for _, event := range missed {
+			if err := send(streamEvent(event)); err != nil {
+				return
+			}
+		}
+	}
+	if err := controller.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-req.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(responseWriter, ": ping\n\n"); err != nil {
+				return
+			}
+		case event, ok := <-sub.Events():
+			if !ok {
+				// Evicted for falling behind, the client reconnects and resumes.
+				return
+			}
+			if err := send(event); err != nil {
+				return
+			}
+		}
+		if err := controller.Flush(); err != nil {
+			return
+		}
+	}
+}
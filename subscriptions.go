@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/anantashahane/Chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// Statuses stored in subscriptions.status.
+const (
+	subscriptionActive   = "active"
+	subscriptionPastDue  = "past_due"
+	subscriptionCanceled = "canceled"
+	subscriptionExpired  = "expired"
+)
+
+const defaultPlan = "red"
+
+// subscriptionIsRed decides Chirpy Red status. A subscription stays Red
+// until its current period ends, even once canceled or while a payment is
+// failing; without a period end it is open ended.
+func subscriptionIsRed(subscription database.Subscription, now time.Time) bool {
+	if subscription.Status == subscriptionExpired {
+		return false
+	}
+	return !subscription.CurrentPeriodEnd.Valid || subscription.CurrentPeriodEnd.Time.After(now)
+}
+
+// applySubscriptionEvent moves the user's subscription through its lifecycle
+// and keeps users.is_chirpy_red in step, in one transaction. sentAt is when
+// Polka sent the event, if known; an event sent before the last one applied
+// is out of date and skipped with errStalePolkaEvent.
+func (apiCfg *apiConfig) applySubscriptionEvent(ctx context.Context, userID uuid.UUID, eventData polkaEvent, sentAt sql.NullTime) error {
+	tx, err := apiCfg.dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	qtx := apiCfg.db.WithTx(tx)
+
+	// Concurrent events for the same user apply one after the other, so the
+	// order check below sees the winner of any race.
+	if err := qtx.LockSubscription(ctx, userID); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	subscription, err := qtx.GetSubscription(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		subscription = database.Subscription{UserID: userID, CreatedAt: now, Plan: defaultPlan}
+	} else if err != nil {
+		return err
+	}
+	hadSubscription := err == nil
+	if sentAt.Valid && subscription.LastEventAt.Valid {
+		if sentAt.Time.Before(subscription.LastEventAt.Time) {
+			return errStalePolkaEvent
+		}
+	}
+	if sentAt.Valid {
+		subscription.LastEventAt = sentAt
+	}
+
+	periodEnd := subscription.CurrentPeriodEnd
+	if eventData.Data.CurrentPeriodEnd != nil {
+		periodEnd = sql.NullTime{Time: *eventData.Data.CurrentPeriodEnd, Valid: true}
+	}
+	if eventData.Data.Plan != "" {
+		subscription.Plan = eventData.Data.Plan
+	}
+
+	switch eventData.Event {
+	case "user.upgraded", "subscription.renewed":
+		// Keeping the old period end would leave a renewed subscription to
+		// lapse on the date it was just renewed past.
+		if eventData.Event == "subscription.renewed" && eventData.Data.CurrentPeriodEnd == nil {
+			return fmt.Errorf("Renewal without current_period_end.")
+		}
+		subscription.Status = subscriptionActive
+		subscription.CurrentPeriodEnd = periodEnd
+		subscription.CanceledAt = sql.NullTime{}
+	case "payment.failed":
+		if !hadSubscription {
+			return fmt.Errorf("No subscription for user %s.", userID)
+		}
+		subscription.Status = subscriptionPastDue
+	case "subscription.canceled":
+		if !hadSubscription {
+			return fmt.Errorf("No subscription for user %s.", userID)
+		}
+		// Canceled subscriptions run to the end of the paid period.
+		if subscription.Status != subscriptionCanceled || !subscription.CanceledAt.Valid {
+			subscription.CanceledAt = sql.NullTime{Time: now, Valid: true}
+		}
+		subscription.Status = subscriptionCanceled
+		subscription.CurrentPeriodEnd = periodEnd
+		if !periodEnd.Valid || !periodEnd.Time.After(now) {
+			subscription.Status = subscriptionExpired
+		}
+	case "user.downgraded":
+		subscription.Status = subscriptionExpired
+		subscription.CurrentPeriodEnd = sql.NullTime{Time: now, Valid: true}
+	default:
+		return errUnknownPolkaEvent
+	}
+
+	subscription, err = qtx.UpsertSubscription(ctx, database.UpsertSubscriptionParams{
+		UserID:           subscription.UserID,
+		CreatedAt:        subscription.CreatedAt,
+		UpdatedAt:        now,
+		Plan:             subscription.Plan,
+		Status:           subscription.Status,
+		CurrentPeriodEnd: subscription.CurrentPeriodEnd,
+		CanceledAt:       subscription.CanceledAt,
+		LastEventAt:      subscription.LastEventAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = qtx.SetChirpyRed(ctx, database.SetChirpyRedParams{
+		IsChirpyRed: sql.NullBool{Bool: subscriptionIsRed(subscription, now), Valid: true},
+		ID:          userID,
+	})
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// expireLapsedSubscriptions ends Red for subscriptions whose period ended
+// without a renewal.
+func (cfg *apiConfig) expireLapsedSubscriptions(ctx context.Context) {
+	expired, err := cfg.db.ExpireLapsedSubscriptions(ctx, time.Now())
+	if err != nil {
+		fmt.Println("Subscription expiry failed: " + err.Error())
+		return
+	}
+	if len(expired) > 0 {
+		fmt.Printf("Expired %d lapsed subscription(s).\n", len(expired))
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/anantashahane/Chirpy/internal/database"
+	"github.com/anantashahane/Chirpy/internal/jobs"
+	"github.com/google/uuid"
+)
+
+// chirpGoneWriter answers 410 with a tombstone for a chirp in the trash, so
+// clients can tell a deleted chirp from one that never existed.
+func chirpGoneWriter(responseWriter http.ResponseWriter, tombstone database.GetChirpTombstoneRow) {
+	type responseBody struct {
+		ID        string `json:"id"`
+		Deleted   bool   `json:"deleted"`
+		DeletedAt string `json:"deleted_at"`
+	}
+
+	responseWriter.Header().Set("Content-Type", "application/json")
+	responseWriter.WriteHeader(410)
+	json.NewEncoder(responseWriter).Encode(responseBody{
+		ID:        tombstone.ID.String(),
+		Deleted:   true,
+		DeletedAt: tombstone.DeletedAt.Time.String(),
+	})
+}
+
+func (apiCfg *apiConfig) getTrashHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	userData, _ := userFromContext(req.Context())
+
+	chirps, err := apiCfg.db.GetTrashedChirps(context.Background(), database.GetTrashedChirpsParams{
+		UserID:    userData.ID,
+		DeletedAt: apiCfg.trashCutoff(),
+	})
+	if err != nil {
+		responseWriter.WriteHeader(500)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Internal Server failed to access database."))
+		return
+	}
+
+	responseBody := []chirpResponseBody{}
+	for _, chirp := range chirps {
+		responseBody = append(responseBody, chirpResponse(chirp))
+	}
+	responseWriter.Header().Set("Content-Type", "application/json")
+	responseWriter.WriteHeader(200)
+	json.NewEncoder(responseWriter).Encode(responseBody)
+}
+
+func (apiCfg *apiConfig) restoreChirpHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	userData, _ := userFromContext(req.Context())
+
+	chirpID, err := uuid.Parse(req.PathValue("chirpID"))
+	if err != nil {
+		responseWriter.WriteHeader(404)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Error parsing chirp id."))
+		return
+	}
+
+	var restored database.Chirp
+	err = apiCfg.inTx(context.Background(), func(q *database.Queries) error {
+		var err error
+		restored, err = q.RestoreChirp(context.Background(), database.RestoreChirpParams{
+			ID:        chirpID,
+			UserID:    userData.ID,
+			UpdatedAt: time.Now(),
+			DeletedAt: apiCfg.trashCutoff(),
+		})
+		if err != nil {
+			return err
+		}
+		if !restored.Published {
+			// Its publish job found it deleted and gave up, so queue another.
+			// A publish time that has passed publishes it straight away.
+			_, err = jobs.Enqueue(context.Background(), q, jobPublishChirp, publishChirpJob{ChirpID: restored.ID}, jobs.Options{
+				RunAt: restored.PublishAt.Time,
+			})
+			return err
+		}
+		return emitEvent(context.Background(), q, "chirp.restored", restored, chirpResponse(restored))
+	})
+	if err != nil {
+		responseWriter.WriteHeader(404)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("No chirp with ID " + chirpID.String() + " in your trash"))
+		return
+	}
+
+	responseWriter.Header().Set("Content-Type", "application/json")
+	responseWriter.WriteHeader(200)
+	json.NewEncoder(responseWriter).Encode(chirpResponse(restored))
+}
+
+// trashCutoff is when chirps deleted before it left the trash for good. The
+// purge job only catches up with it now and then, so reads and restores
+// check it themselves.
+func (cfg *apiConfig) trashCutoff() sql.NullTime {
+	return sql.NullTime{Time: time.Now().Add(-cfg.trashRetention), Valid: true}
+}
+
+// purgeTrashedChirps hard deletes chirps that have been in the trash longer
+// than the retention period.
+func (cfg *apiConfig) purgeTrashedChirps(ctx context.Context) {
+	err := cfg.db.PurgeTrashedChirps(ctx, cfg.trashCutoff())
+	if err != nil {
+		fmt.Println("Trash purge failed: " + err.Error())
+	}
+}
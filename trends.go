@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/anantashahane/Chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	// trendLimit is how many hashtags and chirps are kept per window.
+	trendLimit = 50
+	// Bookmarks are private, so a chirp's score is rounded down to a
+	// multiple of trendScoreStep bookmarks' weight and chirps below one step
+	// are left out. The ranking moves a whole step at a time, not with each
+	// bookmark.
+	trendScoreStep       = 3
+	trendRefreshInterval = 5 * time.Minute
+	trendCacheTTL        = time.Minute
+)
+
+// trendWindows are the sliding windows trends are computed over. Activity
+// loses half its weight every quarter window, so recent bursts outrank
+// steady chatter.
+var trendWindows = map[string]time.Duration{
+	"1h":  time.Hour,
+	"24h": 24 * time.Hour,
+}
+
+// trendSnapshot is one window's trends as last read from the database.
+type trendSnapshot struct {
+	hashtags []database.TrendingHashtag
+	chirps   []database.GetTrendingChirpsRow
+}
+
+// refreshTrends recomputes every window into the trending tables. Each
+// window is replaced in one transaction, so readers never see half of it.
+// Replicas refresh a window one at a time, and one that computed before the
+// rows already there leaves them alone.
+func (cfg *apiConfig) refreshTrends(ctx context.Context) {
+	for name, window := range trendWindows {
+		computedAt := time.Now()
+		err := cfg.inTx(ctx, func(q *database.Queries) error {
+			if err := q.LockTrendingPeriod(ctx, name); err != nil {
+				return err
+			}
+			err := q.RefreshTrendingHashtags(ctx, database.RefreshTrendingHashtagsParams{
+				Period:          name,
+				ComputedAt:      computedAt,
+				HalfLifeSeconds: (window / 4).Seconds(),
+				Since:           computedAt.Add(-window),
+				MaxResults:      trendLimit,
+			})
+			if err != nil {
+				return err
+			}
+			err = q.RefreshTrendingChirps(ctx, database.RefreshTrendingChirpsParams{
+				Period:          name,
+				ComputedAt:      computedAt,
+				HalfLifeSeconds: (window / 4).Seconds(),
+				ScoreStep:       trendScoreStep,
+				Since:           computedAt.Add(-window),
+				MaxResults:      trendLimit,
+			})
+			if err != nil {
+				return err
+			}
+			err = q.DeleteStaleTrendingHashtags(ctx, database.DeleteStaleTrendingHashtagsParams{
+				Period:     name,
+				ComputedAt: computedAt,
+			})
+			if err != nil {
+				return err
+			}
+			return q.DeleteStaleTrendingChirps(ctx, database.DeleteStaleTrendingChirpsParams{
+				Period:     name,
+				ComputedAt: computedAt,
+			})
+		})
+		if err != nil {
+			fmt.Println("Trend refresh failed for " + name + ": " + err.Error())
+		}
+	}
+}
+
+// trends returns a window's trends, from the in-process cache while it is
+// fresh.
+func (apiCfg *apiConfig) trends(window string) (trendSnapshot, error) {
+	if snapshot, ok := apiCfg.trendCache.Get(window, time.Now()); ok {
+		return snapshot, nil
+	}
+
+	hashtags, err := apiCfg.db.GetTrendingHashtags(context.Background(), database.GetTrendingHashtagsParams{
+		Period:     window,
+		MaxResults: trendLimit,
+	})
+	if err != nil {
+		return trendSnapshot{}, err
+	}
+	chirps, err := apiCfg.db.GetTrendingChirps(context.Background(), database.GetTrendingChirpsParams{
+		Period:     window,
+		MaxResults: trendLimit,
+	})
+	if err != nil {
+		return trendSnapshot{}, err
+	}
+
+	snapshot := trendSnapshot{hashtags: hashtags, chirps: chirps}
+	apiCfg.trendCache.Set(window, snapshot, time.Now())
+	return snapshot, nil
+}
+
+type trendingHashtagResponseBody struct {
+	Hashtag     string  `json:"hashtag"`
+	Score       float64 `json:"score"`
+	ChirpCount  int64   `json:"chirp_count"`
+	AuthorCount int64   `json:"author_count"`
+}
+
+type trendingChirpResponseBody struct {
+	chirpResponseBody
+	Score float64 `json:"score"`
+}
+
+// trendsHandler serves the trending hashtags and chirps of a window. Chirps
+// by users the viewer blocked, was blocked by or muted are left out, as are
+// sensitive ones unless the viewer wants them.
+func (apiCfg *apiConfig) trendsHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	type responseBody struct {
+		Window     string                        `json:"window"`
+		ComputedAt string                        `json:"computed_at,omitempty"`
+		Hashtags   []trendingHashtagResponseBody `json:"hashtags"`
+		Chirps     []trendingChirpResponseBody   `json:"chirps"`
+	}
+
+	window := req.URL.Query().Get("window")
+	if window == "" {
+		window = "24h"
+	}
+	if _, ok := trendWindows[window]; !ok {
+		responseWriter.WriteHeader(400)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Window must be 1h or 24h."))
+		return
+	}
+	limit, err := strconv.Atoi(req.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 || limit > trendLimit {
+		limit = 10
+	}
+
+	snapshot, err := apiCfg.trends(window)
+	if err != nil {
+		responseWriter.WriteHeader(500)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Internal Server failed to access database."))
+		return
+	}
+
+	viewerID := apiCfg.viewerFromRequest(req)
+	includeSensitive, status, err := apiCfg.includeSensitive(req, viewerID)
+	if err != nil {
+		responseWriter.WriteHeader(status)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte(err.Error()))
+		return
+	}
+
+	hidden := []uuid.UUID{}
+	if viewerID != uuid.Nil {
+		hidden, err = apiCfg.db.GetHiddenAuthorIDs(context.Background(), viewerID)
+		if err != nil {
+			responseWriter.WriteHeader(500)
+			responseWriter.Header().Set("Content-Type", "plain/text")
+			responseWriter.Write([]byte("Internal Server failed to access database."))
+			return
+		}
+	}
+
+	responseData := responseBody{
+		Window:   window,
+		Hashtags: []trendingHashtagResponseBody{},
+		Chirps:   []trendingChirpResponseBody{},
+	}
+	var computedAt time.Time
+	for _, hashtag := range snapshot.hashtags {
+		computedAt = hashtag.ComputedAt
+		if len(responseData.Hashtags) < limit {
+			responseData.Hashtags = append(responseData.Hashtags, trendingHashtagResponseBody{
+				Hashtag:     hashtag.Hashtag,
+				Score:       hashtag.Score,
+				ChirpCount:  hashtag.ChirpCount,
+				AuthorCount: hashtag.AuthorCount,
+			})
+		}
+	}
+	for _, chirp := range snapshot.chirps {
+		computedAt = chirp.ComputedAt
+		sensitive := chirp.Chirp.Sensitive || chirp.Chirp.ContentWarning != ""
+		if sensitive && !includeSensitive && chirp.Chirp.UserID != viewerID {
+			continue
+		}
+		if len(responseData.Chirps) < limit && !slices.Contains(hidden, chirp.Chirp.UserID) {
+			responseData.Chirps = append(responseData.Chirps, trendingChirpResponseBody{
+				chirpResponseBody: chirpResponse(chirp.Chirp),
+				Score:             chirp.Score,
+			})
+		}
+	}
+	if !computedAt.IsZero() {
+		responseData.ComputedAt = computedAt.String()
+	}
+
+	responseWriter.Header().Set("Content-Type", "application/json")
+	responseWriter.Header().Set("Cache-Control", "private, max-age=60")
+	responseWriter.WriteHeader(200)
+	json.NewEncoder(responseWriter).Encode(responseData)
+}
@@ -104,27 +104,25 @@ func (apiCfg *apiConfig) loginUserHandler(responseWriter http.ResponseWriter, re
 		return
 	}
 
-	token, err := auth.MakeJWT(userData.ID, apiCfg.secret, time.Hour)
-	if match := auth.PasswordMatchesHash(requestedData.Password, userData.Password); !match {
-		userErrorWriter(&responseWriter, encoder, "Authentication error user from token", 403)
-		return
+	// Upgrade hashes made under an older, weaker policy while we have the password.
+	if auth.PasswordNeedsRehash(userData.Password) {
+		if hash, err := auth.HashPassword(requestedData.Password); err == nil {
+			err = apiCfg.db.UpdatePasswordHash(context.Background(), database.UpdatePasswordHashParams{
+				Password: hash,
+				ID:       userData.ID,
+			})
+			if err != nil {
+				fmt.Println("Unable to rehash password for " + userData.Email + ": " + err.Error())
+			}
+		}
 	}
 
-	refreshToken, err := auth.MakeRefreshedToken()
-	if match := auth.PasswordMatchesHash(requestedData.Password, userData.Password); !match {
-		userErrorWriter(&responseWriter, encoder, "Error generating refresh tokens", 503)
+	token, refreshToken, err := apiCfg.issueTokens(userData.ID)
+	if err != nil {
+		userErrorWriter(&responseWriter, encoder, "Error generating tokens: "+err.Error(), 503)
 		return
 	}
 
-	apiCfg.db.CreateRefreshToken(context.Background(), database.CreateRefreshTokenParams{
-		Tokens:    refreshToken,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-		UserID:    userData.ID,
-		ExpiresAt: time.Now().Add(time.Hour * 24 * 60),
-		RevokedAt: sql.NullTime{},
-	})
-
 	responseData := userDataResponse{
 		ID:           userData.ID.String(),
 		CreatedAt:    userData.CreatedAt.String(),
@@ -142,6 +140,42 @@ func (apiCfg *apiConfig) loginUserHandler(responseWriter http.ResponseWriter, re
 	}
 }
 
+// issueTokens signs a fresh access token and stores a new refresh token for
+// the user, as handed out on every successful sign in.
+// Signing in also cancels a pending account deletion.
+func (apiCfg *apiConfig) issueTokens(userID uuid.UUID) (string, string, error) {
+	err := apiCfg.db.CancelUserDeletion(context.Background(), database.CancelUserDeletionParams{
+		UpdatedAt: time.Now(),
+		ID:        userID,
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	token, err := auth.MakeJWT(userID, apiCfg.secret, time.Hour)
+	if err != nil {
+		return "", "", err
+	}
+
+	refreshToken, err := auth.MakeRefreshedToken()
+	if err != nil {
+		return "", "", err
+	}
+
+	_, err = apiCfg.db.CreateRefreshToken(context.Background(), database.CreateRefreshTokenParams{
+		Tokens:    refreshToken,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(time.Hour * 24 * 60),
+		RevokedAt: sql.NullTime{},
+	})
+	if err != nil {
+		return "", "", err
+	}
+	return token, refreshToken, nil
+}
+
 func (apiCfg *apiConfig) handleRefresh(responseWriter http.ResponseWriter, req *http.Request) {
 	type responseBody struct {
 		Token string `json:"token"`
@@ -265,56 +299,106 @@ func (apiCfg *apiConfig) handleUserPasswordChange(responseWriter http.ResponseWr
 	encoder.Encode(responseData)
 }
 
-func (apiCfg *apiConfig) upgradeUserHandler(responseWriter http.ResponseWriter, req *http.Request) {
-	polkaKey, err := auth.GetPolkaKey(req.Header)
-
-	type UserUpgradeJson struct {
-		Event string `json:"event"`
-		Data  struct {
-			UserID string `json:"user_id"`
-		} `json:"data"`
+func (apiCfg *apiConfig) deleteOwnAccountHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	type requestBody struct {
+		Password string `json:"password"`
 	}
+	type responseBody struct {
+		DeletionRequestedAt string `json:"deletion_requested_at"`
+		DeletesAt           string `json:"deletes_at"`
+	}
+
+	encoder := json.NewEncoder(responseWriter)
+	decoder := json.NewDecoder(req.Body)
+	decoder.DisallowUnknownFields()
+	defer req.Body.Close()
+
+	userData, _ := userFromContext(req.Context())
 
-	if err != nil || polkaKey != apiCfg.polkaKey {
-		responseWriter.WriteHeader(401)
+	requestedData := requestBody{}
+	err := decoder.Decode(&requestedData)
+	if err != nil {
+		userErrorWriter(&responseWriter, encoder, "Incoming json format too zooted.", 420)
 		return
 	}
 
-	userUpgradeData := UserUpgradeJson{}
+	if userData.Password == "" {
+		userErrorWriter(&responseWriter, encoder, "Account has no password, set one with PUT /api/users first.", 403)
+		return
+	}
+	if match := auth.PasswordMatchesHash(requestedData.Password, userData.Password); !match {
+		userErrorWriter(&responseWriter, encoder, "Incorrect password for user "+userData.Email, 401)
+		return
+	}
 
-	decoder := json.NewDecoder(req.Body)
-	defer req.Body.Close()
+	now := time.Now()
+	userData, err = apiCfg.db.RequestUserDeletion(context.Background(), database.RequestUserDeletionParams{
+		DeletionRequestedAt: sql.NullTime{Time: now, Valid: true},
+		UpdatedAt:           now,
+		ID:                  userData.ID,
+	})
+	if err != nil {
+		userErrorWriter(&responseWriter, encoder, "Unable to schedule deletion: "+err.Error(), 503)
+		return
+	}
 
-	err = decoder.Decode(&userUpgradeData)
+	err = apiCfg.db.RevokeUserTokens(context.Background(), database.RevokeUserTokensParams{
+		RevokedAt: sql.NullTime{Time: now, Valid: true},
+		UserID:    userData.ID,
+	})
 	if err != nil {
-		fmt.Println("Unable to decode data.")
-		responseWriter.WriteHeader(404)
+		userErrorWriter(&responseWriter, encoder, "Unable to revoke sessions: "+err.Error(), 503)
 		return
 	}
 
-	if userUpgradeData.Event == "user.upgraded" {
-		uid, err := uuid.Parse(userUpgradeData.Data.UserID)
-		if err != nil {
-			fmt.Println("Unable to parse data.")
-			responseWriter.WriteHeader(404)
-			return
-		}
-		userInDB, err := apiCfg.db.GetUserByID(context.Background(), uid)
-		if err != nil {
-			fmt.Println("User not in DB.")
-			responseWriter.WriteHeader(404)
-			return
-		}
-		_, err = apiCfg.db.UpgradeUsertoRed(context.Background(), userInDB.ID)
-		if err != nil {
-			fmt.Println("Unable to upgrade user in DB.")
-			responseWriter.WriteHeader(404)
-			return
-		}
-	} else {
-		fmt.Println("Unknown Event " + userUpgradeData.Event)
-		responseWriter.WriteHeader(204)
+	responseWriter.WriteHeader(202)
+	encoder.Encode(responseBody{
+		DeletionRequestedAt: userData.DeletionRequestedAt.Time.String(),
+		DeletesAt:           userData.DeletionRequestedAt.Time.Add(apiCfg.deletionGracePeriod).String(),
+	})
+}
+
+type preferencesBody struct {
+	HideSensitive bool `json:"hide_sensitive"`
+}
+
+func (apiCfg *apiConfig) getPreferencesHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	userData, _ := userFromContext(req.Context())
+
+	responseWriter.Header().Set("Content-Type", "application/json")
+	responseWriter.WriteHeader(200)
+	json.NewEncoder(responseWriter).Encode(preferencesBody{HideSensitive: userData.HideSensitive})
+}
+
+// updatePreferencesHandler replaces the caller's profile preferences.
+// hide_sensitive leaves chirps with a content warning or marked sensitive
+// out of feeds unless a request asks for them.
+func (apiCfg *apiConfig) updatePreferencesHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	userData, _ := userFromContext(req.Context())
+
+	decoder := json.NewDecoder(req.Body)
+	decoder.DisallowUnknownFields()
+	requestData := preferencesBody{}
+	if err := decoder.Decode(&requestData); err != nil {
+		responseWriter.WriteHeader(400)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Json Decode failed."))
 		return
 	}
-	responseWriter.WriteHeader(204)
+
+	updated, err := apiCfg.db.SetUserHideSensitive(context.Background(), database.SetUserHideSensitiveParams{
+		HideSensitive: requestData.HideSensitive,
+		UpdatedAt:     time.Now(),
+		ID:            userData.ID,
+	})
+	if err != nil {
+		responseWriter.WriteHeader(500)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Unable to save preferences."))
+		return
+	}
+
+	responseWriter.Header().Set("Content-Type", "application/json")
+	responseWriter.WriteHeader(200)
+	json.NewEncoder(responseWriter).Encode(preferencesBody{HideSensitive: updated.HideSensitive})
 }
@@ -0,0 +1,423 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/anantashahane/Chirpy/internal/auth"
+	"github.com/anantashahane/Chirpy/internal/database"
+	"github.com/anantashahane/Chirpy/internal/egress"
+	"github.com/anantashahane/Chirpy/internal/jobs"
+	"github.com/google/uuid"
+)
+
+// Events integrations can subscribe to.
+var webhookEventTypes = []string{"chirp.created", "chirp.updated", "chirp.deleted", "chirp.restored"}
+
+// Statuses stored in webhook_deliveries.status.
+const (
+	deliveryPending   = "pending"
+	deliverySucceeded = "succeeded"
+	deliveryDead      = "dead"
+)
+
+const (
+	// webhookTimeout bounds a single delivery, including reading the answer.
+	webhookTimeout = 10 * time.Second
+	// webhookBatchSize is how many deliveries one pass claims and sends in
+	// turn.
+	webhookBatchSize = 10
+)
+
+type webhookEndpointResponseBody struct {
+	ID        string   `json:"id"`
+	CreatedAt string   `json:"created_at"`
+	URL       string   `json:"url"`
+	Events    []string `json:"events"`
+	Global    bool     `json:"global"`
+	Secret    string   `json:"secret,omitempty"`
+}
+
+type webhookDeliveryResponseBody struct {
+	ID             string `json:"id"`
+	CreatedAt      string `json:"created_at"`
+	UpdatedAt      string `json:"updated_at"`
+	EventID        string `json:"event_id"`
+	Event          string `json:"event"`
+	Status         string `json:"status"`
+	Attempts       int32  `json:"attempts"`
+	NextAttemptAt  string `json:"next_attempt_at,omitempty"`
+	LastError      string `json:"last_error,omitempty"`
+	ResponseStatus int32  `json:"response_status,omitempty"`
+}
+
+func webhookEndpointResponse(endpoint database.WebhookEndpoint) webhookEndpointResponseBody {
+	return webhookEndpointResponseBody{
+		ID:        endpoint.ID.String(),
+		CreatedAt: endpoint.CreatedAt.String(),
+		URL:       endpoint.Url,
+		Events:    endpoint.Events,
+		Global:    endpoint.Global,
+	}
+}
+
+func (apiCfg *apiConfig) createWebhookEndpointHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	type requestBody struct {
+		URL    string   `json:"url"`
+		Events []string `json:"events"`
+		Global bool     `json:"global"`
+	}
+
+	userData, _ := userFromContext(req.Context())
+
+	decoder := json.NewDecoder(req.Body)
+	decoder.DisallowUnknownFields()
+	requestData := requestBody{}
+	if err := decoder.Decode(&requestData); err != nil {
+		responseWriter.WriteHeader(400)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Json Decode failed."))
+		return
+	}
+
+	target, err := url.Parse(requestData.URL)
+	if err != nil || (target.Scheme != "https" && target.Scheme != "http") || target.Host == "" {
+		responseWriter.WriteHeader(400)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("url must be an absolute http(s) URL."))
+		return
+	}
+	// Deliveries are refused at dial time too, this just fails early.
+	if err := egress.CheckHost(req.Context(), target.Hostname()); err != nil {
+		responseWriter.WriteHeader(400)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("url must resolve to public addresses only."))
+		return
+	}
+	if len(requestData.Events) == 0 {
+		responseWriter.WriteHeader(400)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Subscribe to at least one event."))
+		return
+	}
+	for _, event := range requestData.Events {
+		if !slices.Contains(webhookEventTypes, event) {
+			responseWriter.WriteHeader(400)
+			responseWriter.Header().Set("Content-Type", "plain/text")
+			responseWriter.Write([]byte("Unknown event " + event))
+			return
+		}
+	}
+	if requestData.Global && userData.Role != roleAdmin {
+		responseWriter.WriteHeader(403)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Only admins can subscribe to every user's events."))
+		return
+	}
+
+	secret, err := auth.MakeRefreshedToken()
+	if err != nil {
+		responseWriter.WriteHeader(503)
+		return
+	}
+
+	endpoint, err := apiCfg.db.CreateWebhookEndpoint(context.Background(), database.CreateWebhookEndpointParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		UserID:    userData.ID,
+		Url:       target.String(),
+		Secret:    "whsec_" + secret,
+		Events:    requestData.Events,
+		Global:    requestData.Global,
+	})
+	if err != nil {
+		responseWriter.WriteHeader(500)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Unable to save webhook."))
+		return
+	}
+
+	// The secret is only ever shown once, on creation.
+	responseData := webhookEndpointResponse(endpoint)
+	responseData.Secret = endpoint.Secret
+	responseWriter.Header().Set("Content-Type", "application/json")
+	responseWriter.WriteHeader(201)
+	json.NewEncoder(responseWriter).Encode(responseData)
+}
+
+func (apiCfg *apiConfig) listWebhookEndpointsHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	userData, _ := userFromContext(req.Context())
+
+	endpoints, err := apiCfg.db.ListWebhookEndpoints(context.Background(), userData.ID)
+	if err != nil {
+		responseWriter.WriteHeader(500)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Internal Server failed to access database."))
+		return
+	}
+
+	responseBody := []webhookEndpointResponseBody{}
+	for _, endpoint := range endpoints {
+		responseBody = append(responseBody, webhookEndpointResponse(endpoint))
+	}
+	responseWriter.Header().Set("Content-Type", "application/json")
+	responseWriter.WriteHeader(200)
+	json.NewEncoder(responseWriter).Encode(responseBody)
+}
+
+func (apiCfg *apiConfig) deleteWebhookEndpointHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	userData, _ := userFromContext(req.Context())
+
+	webhookID, err := uuid.Parse(req.PathValue("webhookID"))
+	if err != nil {
+		responseWriter.WriteHeader(404)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Error parsing webhook id."))
+		return
+	}
+
+	_, err = apiCfg.db.DeleteWebhookEndpoint(context.Background(), database.DeleteWebhookEndpointParams{
+		ID:     webhookID,
+		UserID: userData.ID,
+	})
+	if err != nil {
+		responseWriter.WriteHeader(404)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("No such webhook " + webhookID.String()))
+		return
+	}
+	responseWriter.WriteHeader(204)
+}
+
+func (apiCfg *apiConfig) listWebhookDeliveriesHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	userData, _ := userFromContext(req.Context())
+
+	webhookID, err := uuid.Parse(req.PathValue("webhookID"))
+	if err != nil {
+		responseWriter.WriteHeader(404)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Error parsing webhook id."))
+		return
+	}
+
+	endpoint, err := apiCfg.db.GetWebhookEndpoint(context.Background(), webhookID)
+	if err != nil || endpoint.UserID != userData.ID {
+		responseWriter.WriteHeader(404)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("No such webhook " + webhookID.String()))
+		return
+	}
+
+	limit, err := strconv.Atoi(req.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 || limit > 500 {
+		limit = 100
+	}
+	deliveries, err := apiCfg.db.ListWebhookDeliveries(context.Background(), database.ListWebhookDeliveriesParams{
+		EndpointID: endpoint.ID,
+		Limit:      int32(limit),
+	})
+	if err != nil {
+		responseWriter.WriteHeader(500)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Internal Server failed to access database."))
+		return
+	}
+
+	responseBody := []webhookDeliveryResponseBody{}
+	for _, delivery := range deliveries {
+		responseData := webhookDeliveryResponseBody{
+			ID:             delivery.ID.String(),
+			CreatedAt:      delivery.CreatedAt.String(),
+			UpdatedAt:      delivery.UpdatedAt.String(),
+			EventID:        delivery.EventID.String(),
+			Event:          delivery.Event,
+			Status:         delivery.Status,
+			Attempts:       delivery.Attempts,
+			LastError:      delivery.LastError.String,
+			ResponseStatus: delivery.ResponseStatus.Int32,
+		}
+		if delivery.Status == deliveryPending {
+			responseData.NextAttemptAt = delivery.NextAttemptAt.String()
+		}
+		responseBody = append(responseBody, responseData)
+	}
+	responseWriter.Header().Set("Content-Type", "application/json")
+	responseWriter.WriteHeader(200)
+	json.NewEncoder(responseWriter).Encode(responseBody)
+}
+
+// webhookEventJob is the outbox entry for one event, fanned out to the
+// subscribed endpoints by fanOutWebhookEvent. Public is set for events about
+// public chirps, the only ones global endpoints receive.
+type webhookEventJob struct {
+	EventID   uuid.UUID       `json:"event_id"`
+	Event     string          `json:"event"`
+	ActorID   uuid.UUID       `json:"actor_id"`
+	Public    bool            `json:"public"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// emitEvent records event about chirp in the outbox through q. Pass the
+// Queries of the transaction making the change so the event exists only if
+// the change does.
+func emitEvent(ctx context.Context, q *database.Queries, event string, chirp database.Chirp, data any) error {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = jobs.Enqueue(ctx, q, jobFanOutWebhookEvent, webhookEventJob{
+		EventID:   uuid.New(),
+		Event:     event,
+		ActorID:   chirp.UserID,
+		Public:    chirp.Visibility == visibilityPublic,
+		CreatedAt: time.Now(),
+		Data:      encoded,
+	}, jobs.Options{})
+	return err
+}
+
+// fanOutWebhookEvent queues a delivery of the event to every endpoint
+// subscribed to it, either the actor's own or, for public chirps, a global
+// one.
+func (cfg *apiConfig) fanOutWebhookEvent(ctx context.Context, eventData webhookEventJob) error {
+	payload, err := json.Marshal(struct {
+		ID        string          `json:"id"`
+		Event     string          `json:"event"`
+		CreatedAt string          `json:"created_at"`
+		Data      json.RawMessage `json:"data"`
+	}{
+		ID:        eventData.EventID.String(),
+		Event:     eventData.Event,
+		CreatedAt: eventData.CreatedAt.UTC().Format(time.RFC3339),
+		Data:      eventData.Data,
+	})
+	if err != nil {
+		return jobs.Permanent(err)
+	}
+
+	return cfg.inTx(ctx, func(q *database.Queries) error {
+		endpoints, err := q.GetWebhookEndpointsForEvent(ctx, database.GetWebhookEndpointsForEventParams{
+			Event:  eventData.Event,
+			UserID: eventData.ActorID,
+			Public: eventData.Public,
+		})
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		for _, endpoint := range endpoints {
+			_, err := q.CreateWebhookDelivery(ctx, database.CreateWebhookDeliveryParams{
+				ID:            uuid.New(),
+				CreatedAt:     now,
+				UpdatedAt:     now,
+				EndpointID:    endpoint.ID,
+				EventID:       eventData.EventID,
+				Event:         eventData.Event,
+				Payload:       string(payload),
+				NextAttemptAt: now,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// webhookBackoff is the wait before the next attempt: 30s doubling per
+// attempt, capped at six hours.
+func webhookBackoff(attempts int32) time.Duration {
+	backoff := 30 * time.Second
+	for i := int32(1); i < attempts && backoff < 6*time.Hour; i++ {
+		backoff *= 2
+	}
+	return min(backoff, 6*time.Hour)
+}
+
+// deliverWebhooks sends due deliveries. Claimed rows are leased for long
+// enough to send the whole batch one after another, plus a minute to spare,
+// so other replicas skip them until this pass is done with them.
+func (cfg *apiConfig) deliverWebhooks(ctx context.Context) {
+	now := time.Now()
+	deliveries, err := cfg.db.ClaimDueWebhookDeliveries(ctx, database.ClaimDueWebhookDeliveriesParams{
+		LeaseUntil: now.Add(webhookBatchSize*webhookTimeout + time.Minute),
+		Now:        now,
+		MaxResults: webhookBatchSize,
+	})
+	if err != nil {
+		fmt.Println("Unable to claim webhook deliveries: " + err.Error())
+		return
+	}
+	for _, delivery := range deliveries {
+		cfg.deliverWebhook(ctx, delivery)
+	}
+}
+
+func (cfg *apiConfig) deliverWebhook(ctx context.Context, delivery database.WebhookDelivery) {
+	responseStatus := sql.NullInt32{}
+	deliveryErr := func() error {
+		endpoint, err := cfg.db.GetWebhookEndpoint(ctx, delivery.EndpointID)
+		if err != nil {
+			return err
+		}
+
+		timestamp := time.Now().Unix()
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.Url, bytes.NewReader([]byte(delivery.Payload)))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", "Chirpy-Webhooks/1")
+		req.Header.Set("Chirpy-Event", delivery.Event)
+		req.Header.Set("Chirpy-Delivery", delivery.ID.String())
+		req.Header.Set("Chirpy-Timestamp", strconv.FormatInt(timestamp, 10))
+		req.Header.Set("Chirpy-Signature", "v1="+auth.SignPayload(endpoint.Secret, timestamp, []byte(delivery.Payload)))
+
+		resp, err := cfg.webhookClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+		responseStatus = sql.NullInt32{Int32: int32(resp.StatusCode), Valid: true}
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("endpoint answered %d", resp.StatusCode)
+		}
+		return nil
+	}()
+
+	now := time.Now()
+	attempt := database.RecordWebhookDeliveryAttemptParams{
+		Status:         deliverySucceeded,
+		NextAttemptAt:  now,
+		ResponseStatus: responseStatus,
+		UpdatedAt:      now,
+		ID:             delivery.ID,
+	}
+	if deliveryErr != nil {
+		attempt.LastError = sql.NullString{String: deliveryErr.Error(), Valid: true}
+		attempt.Status = deliveryPending
+		attempt.NextAttemptAt = now.Add(webhookBackoff(delivery.Attempts + 1))
+		if delivery.Attempts+1 >= cfg.webhookMaxAttempts {
+			attempt.Status = deliveryDead
+		}
+	}
+
+	_, err := cfg.db.RecordWebhookDeliveryAttempt(ctx, attempt)
+	if err != nil {
+		fmt.Println("Unable to record webhook delivery " + delivery.ID.String() + ": " + err.Error())
+	}
+}
@@ -0,0 +1,368 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anantashahane/Chirpy/internal/database"
+	"github.com/anantashahane/Chirpy/internal/pubsub"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// The client has to answer pings, or send something, within wsPongWait.
+	wsPongWait     = 60 * time.Second
+	wsPingInterval = 25 * time.Second
+	wsWriteWait    = 10 * time.Second
+	wsMaxMessage   = 4096
+	// Messages waiting to be written. When the socket can't keep up this
+	// fills, then the subscriptions' buffers do, and the hub evicts them.
+	wsSendBuffer       = 64
+	wsMaxSubscriptions = 20
+	// Clients send typing on every keystroke, each conversation passes on
+	// one per wsTypingInterval.
+	wsTypingInterval = 3 * time.Second
+)
+
+var wsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	// Clients sign in with a Bearer header, not a cookie, so another site
+	// can't open a socket with the user's credentials.
+	CheckOrigin: func(req *http.Request) bool { return true },
+}
+
+// wsClientMessage is anything a client sends.
+type wsClientMessage struct {
+	Type           string `json:"type"`
+	Channel        string `json:"channel"`
+	UserID         string `json:"user_id"`
+	Hashtag        string `json:"hashtag"`
+	ConversationID string `json:"conversation_id"`
+	LastEventID    int64  `json:"last_event_id"`
+}
+
+type wsServerMessage struct {
+	Type         string          `json:"type"`
+	Channel      string          `json:"channel,omitempty"`
+	Subscription string          `json:"subscription,omitempty"`
+	ID           int64           `json:"id,omitempty"`
+	Event        string          `json:"event,omitempty"`
+	Data         json.RawMessage `json:"data,omitempty"`
+	Message      string          `json:"message,omitempty"`
+}
+
+// wsSession is one signed in socket. The handler's goroutine reads, one
+// goroutine writes, and each subscription forwards from the hub.
+type wsSession struct {
+	apiCfg *apiConfig
+	conn   *websocket.Conn
+	userID uuid.UUID
+	hidden []uuid.UUID
+	// hideSensitive leaves sensitive chirps out of timelines, as in feeds.
+	hideSensitive bool
+	send          chan wsServerMessage
+	done          chan struct{}
+	// typingSent is when each conversation last passed on a typing
+	// indicator. Only the reading goroutine touches it.
+	typingSent map[uuid.UUID]time.Time
+
+	mu   sync.Mutex
+	subs map[string]*pubsub.Subscription
+}
+
+// websocketHandler upgrades to a WebSocket that carries live timelines and
+// notifications for the signed in user, see the README for the protocol.
+func (apiCfg *apiConfig) websocketHandler(responseWriter http.ResponseWriter, req *http.Request) {
+	userData, _ := userFromContext(req.Context())
+
+	hidden, err := apiCfg.db.GetHiddenAuthorIDs(context.Background(), userData.ID)
+	if err != nil {
+		responseWriter.WriteHeader(500)
+		responseWriter.Header().Set("Content-Type", "plain/text")
+		responseWriter.Write([]byte("Internal Server failed to access database."))
+		return
+	}
+
+	// Upgrade answers the request itself when it fails.
+	conn, err := wsUpgrader.Upgrade(responseWriter, req, nil)
+	if err != nil {
+		return
+	}
+
+	session := &wsSession{
+		apiCfg:        apiCfg,
+		conn:          conn,
+		userID:        userData.ID,
+		hidden:        hidden,
+		hideSensitive: userData.HideSensitive,
+		send:          make(chan wsServerMessage, wsSendBuffer),
+		done:          make(chan struct{}),
+		subs:          map[string]*pubsub.Subscription{},
+		typingSent:    map[uuid.UUID]time.Time{},
+	}
+	go session.writeLoop()
+	session.readLoop()
+
+	close(session.done)
+	session.mu.Lock()
+	for _, sub := range session.subs {
+		sub.Close()
+	}
+	session.mu.Unlock()
+	conn.Close()
+}
+
+// queue hands message to the writer, waiting while its buffer is full.
+func (session *wsSession) queue(message wsServerMessage) bool {
+	select {
+	case session.send <- message:
+		return true
+	case <-session.done:
+		return false
+	}
+}
+
+func (session *wsSession) queueError(message string) {
+	session.queue(wsServerMessage{Type: "error", Message: message})
+}
+
+func (session *wsSession) writeLoop() {
+	ping := time.NewTicker(wsPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case message := <-session.send:
+			session.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := session.conn.WriteJSON(message); err != nil {
+				// Unblocks the reader, which ends the session.
+				session.conn.Close()
+				return
+			}
+		case <-ping.C:
+			if err := session.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait)); err != nil {
+				session.conn.Close()
+				return
+			}
+		case <-session.done:
+			session.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(wsWriteWait))
+			return
+		}
+	}
+}
+
+func (session *wsSession) readLoop() {
+	session.conn.SetReadLimit(wsMaxMessage)
+	session.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	session.conn.SetPongHandler(func(string) error {
+		return session.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	for {
+		_, data, err := session.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		session.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+
+		message := wsClientMessage{}
+		if err := json.Unmarshal(data, &message); err != nil {
+			session.queueError("Json Decode failed.")
+			continue
+		}
+		switch message.Type {
+		case "subscribe":
+			session.subscribe(message)
+		case "unsubscribe":
+			session.unsubscribe(message)
+		case "ping":
+			session.queue(wsServerMessage{Type: "pong"})
+		case "typing":
+			session.typing(message)
+		default:
+			session.queueError("Unknown message type " + message.Type)
+		}
+	}
+}
+
+// topic resolves what a subscribe or unsubscribe message is about, queueing
+// an error when it can't.
+func (session *wsSession) topic(message wsClientMessage) (string, bool) {
+	switch message.Channel {
+	case "timeline":
+		topic, _, err := session.apiCfg.timelineTopic(session.userID, message.UserID, message.Hashtag)
+		if err != nil {
+			session.queueError(err.Error())
+			return "", false
+		}
+		return topic, true
+	case "notifications":
+		return notificationsTopic(session.userID), true
+	case "conversation", "typing":
+		conversationID, ok := session.conversation(message)
+		if !ok {
+			return "", false
+		}
+		if message.Channel == "typing" {
+			return typingTopic(conversationID), true
+		}
+		return conversationTopic(conversationID), true
+	default:
+		session.queueError("Unknown channel " + message.Channel)
+		return "", false
+	}
+}
+
+// conversation checks the caller is in the conversation a message names,
+// queueing an error when not.
+func (session *wsSession) conversation(message wsClientMessage) (uuid.UUID, bool) {
+	conversationID, err := uuid.Parse(message.ConversationID)
+	if err != nil {
+		session.queueError("Error parsing conversation id.")
+		return uuid.Nil, false
+	}
+	_, err = session.apiCfg.db.GetConversationForMember(context.Background(), database.GetConversationForMemberParams{
+		ID:     conversationID,
+		UserID: session.userID,
+	})
+	if err != nil {
+		session.queueError("No such conversation " + conversationID.String())
+		return uuid.Nil, false
+	}
+	return conversationID, true
+}
+
+// typing tells the conversation's other members, on every replica, that the
+// caller is typing. Indicators coming faster than wsTypingInterval are
+// dropped before touching the database.
+func (session *wsSession) typing(message wsClientMessage) {
+	if conversationID, err := uuid.Parse(message.ConversationID); err == nil && time.Since(session.typingSent[conversationID]) < wsTypingInterval {
+		return
+	}
+	conversationID, ok := session.conversation(message)
+	if !ok {
+		return
+	}
+	session.typingSent[conversationID] = time.Now()
+	payload, err := json.Marshal(typingIndicator{ConversationID: conversationID, UserID: session.userID})
+	if err != nil {
+		return
+	}
+	if err := session.apiCfg.db.NotifyTyping(context.Background(), string(payload)); err != nil {
+		session.queueError("Unable to send typing indicator.")
+	}
+}
+
+func (session *wsSession) subscribe(message wsClientMessage) {
+	topic, ok := session.topic(message)
+	if !ok {
+		return
+	}
+	if message.LastEventID < 0 {
+		session.queueError("Invalid last_event_id.")
+		return
+	}
+
+	session.mu.Lock()
+	if _, subscribed := session.subs[topic]; subscribed {
+		session.mu.Unlock()
+		session.queue(wsServerMessage{Type: "subscribed", Channel: message.Channel, Subscription: topic})
+		return
+	}
+	if len(session.subs) >= wsMaxSubscriptions {
+		session.mu.Unlock()
+		session.queueError("Too many subscriptions.")
+		return
+	}
+	sub := session.apiCfg.streamHub.Subscribe(topic, streamBuffer)
+	session.subs[topic] = sub
+	session.mu.Unlock()
+
+	session.queue(wsServerMessage{Type: "subscribed", Channel: message.Channel, Subscription: topic})
+	go session.forward(message.Channel, topic, sub, message.LastEventID)
+}
+
+func (session *wsSession) unsubscribe(message wsClientMessage) {
+	topic, ok := session.topic(message)
+	if !ok {
+		return
+	}
+
+	session.mu.Lock()
+	if sub, subscribed := session.subs[topic]; subscribed {
+		sub.Close()
+		delete(session.subs, topic)
+	}
+	session.mu.Unlock()
+
+	session.queue(wsServerMessage{Type: "unsubscribed", Channel: message.Channel, Subscription: topic})
+}
+
+// forward replays what the client missed since lastSent, when it gave one,
+// then relays sub's events until it is closed. An evicted subscription is
+// reported with the last ID sent, so the client can subscribe again from
+// there.
+func (session *wsSession) forward(channel, topic string, sub *pubsub.Subscription, lastSent int64) {
+	// Muting someone hides them from feeds and notifications, not from their
+	// own profile or conversations.
+	filtered := topic == "public" || strings.HasPrefix(topic, "hashtag:") || strings.HasPrefix(topic, "notifications:")
+	cursor := streamCursor(lastSent)
+	relay := func(event pubsub.Event) bool {
+		if !cursor.advance(event.ID) {
+			return true
+		}
+		if filtered && slices.Contains(session.hidden, event.ActorID) {
+			return true
+		}
+		if channel == "timeline" && session.hideSensitive && event.ActorID != session.userID && sensitiveEvent(event) {
+			return true
+		}
+		return session.queue(wsServerMessage{
+			Type:         "event",
+			Channel:      channel,
+			Subscription: topic,
+			ID:           event.ID,
+			Event:        event.Type,
+			Data:         json.RawMessage(event.Data),
+		})
+	}
+
+	if lastSent > 0 {
+		missed, err := session.apiCfg.db.GetStreamEventsAfter(context.Background(), database.GetStreamEventsAfterParams{
+			AfterID:    lastSent,
+			Topic:      topic,
+			MaxResults: streamReplayLimit,
+		})
+		if err != nil {
+			session.queueError("Unable to replay missed events.")
+		}
+		for _, event := range missed {
+			if !relay(streamEvent(event)) {
+				return
+			}
+		}
+	}
+
+	for event := range sub.Events() {
+		if !relay(event) {
+			return
+		}
+	}
+	if !sub.Evicted() {
+		return
+	}
+
+	session.mu.Lock()
+	if session.subs[topic] == sub {
+		delete(session.subs, topic)
+	}
+	session.mu.Unlock()
+	session.queue(wsServerMessage{Type: "evicted", Channel: channel, Subscription: topic, ID: int64(cursor)})
+}